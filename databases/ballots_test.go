@@ -0,0 +1,81 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// racedClient is a DynamoDB client whose ballot transactions always lose the
+// race for the ballot box, as if another submission beat every one of them
+type racedClient struct {
+	dynamoClient
+	box          map[string]types.AttributeValue
+	transactions int
+}
+
+// Query finds the question and its one option, "option", on the indexes, and
+// the ballot box in the participant's partition
+func (c *racedClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	switch aws.ToString(input.IndexName) {
+	case "EntityTypeIndex":
+		return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+			"pk": &types.AttributeValueMemberS{Value: "QUESTION#question"},
+			"sk": &types.AttributeValueMemberS{Value: "EVENT#event"},
+		}}}, nil
+	case "QuestionIDIndex":
+		return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{optionKey("option", "question")}}, nil
+	}
+	return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{c.box}}, nil
+}
+
+func (c *racedClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.transactions++
+
+	reasons := make([]types.CancellationReason, len(input.TransactItems))
+	for i := range reasons {
+		reasons[i].Code = aws.String("None")
+	}
+	reasons[0].Code = aws.String("ConditionalCheckFailed")
+	return nil, &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestReplaceBallotsGivesUp(t *testing.T) {
+	box, err := attributevalue.MarshalMap(ballotBox{
+		DynamoItem: models.DynamoItem{PK: models.BallotPartition("question", "participant"), SK: ballotBoxSK},
+		QuestionID: "question",
+		EventID:    "event",
+		EntityType: ballotBoxEntity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &racedClient{box: box}
+	store := &DynamoStore{client: client, table: "planzoco"}
+
+	ballot := models.NewBallot("question", models.NewParticipant("participant", ""), "option")
+	err = store.ReplaceBallots(context.Background(), "question", "participant", []models.Ballot{ballot})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("ReplaceBallots returned %v, want ErrConflict", err)
+	}
+	if client.transactions != maxBallotAttempts {
+		t.Errorf("ReplaceBallots tried %d times, want %d", client.transactions, maxBallotAttempts)
+	}
+
+	client.transactions = 0
+	veto := models.NewVeto("question", models.NewParticipant("participant", ""), "option")
+	err = store.ReplaceVetoes(context.Background(), "question", "participant", []models.Veto{veto})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("ReplaceVetoes returned %v, want ErrConflict", err)
+	}
+	if client.transactions != maxBallotAttempts {
+		t.Errorf("ReplaceVetoes tried %d times, want %d", client.transactions, maxBallotAttempts)
+	}
+}
@@ -0,0 +1,371 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Cascading deletes
+//
+// An event or question is deleted together with everything beneath it. The
+// items being deleted are marked before their children are listed, which
+// hides marked events and questions from reads, and the writes that add
+// children check that their parent is not marked, so nothing can be added in
+// between and left behind. When all of it fits in one transaction it goes at
+// once or not at all, and if the transaction fails the marks are cleared
+// again. Anything bigger cannot be deleted atomically, so the children are
+// deleted in batches and the marked items last. A delete that is cut short
+// leaves the marked items behind, and deleting them again, or ResumeDeletes,
+// picks up where it stopped.
+
+const (
+	// maxBatchWriteItems is the most items DynamoDB accepts in one batch write
+	maxBatchWriteItems = 25
+	// maxBatchAttempts is how often a batch write is retried while DynamoDB
+	// leaves some of its items unprocessed
+	maxBatchAttempts = 5
+)
+
+// itemKey is the primary key of an item, its PK and SK
+type itemKey = map[string]types.AttributeValue
+
+// ResumeDeletes finishes the deletes of events and questions that were cut
+// short, see DeleteEvent
+func (s *DynamoStore) ResumeDeletes(ctx context.Context) error {
+	events, err := s.queryKeys(ctx, s.markedQuery(models.EventEntity))
+	if err != nil {
+		return fmt.Errorf("failed to find events being deleted: %w", err)
+	}
+	for _, key := range events {
+		if err := s.DeleteEvent(ctx, keyID(key, models.EventEntity)); err != nil {
+			return err
+		}
+	}
+
+	questions, err := s.queryKeys(ctx, s.markedQuery(models.QuestionEntity))
+	if err != nil {
+		return fmt.Errorf("failed to find questions being deleted: %w", err)
+	}
+	for _, key := range questions {
+		if err := s.deleteQuestion(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteQuestion deletes the question with the given key along with its
+// options, ballots, vetoes and ballot boxes
+func (s *DynamoStore) deleteQuestion(ctx context.Context, key itemKey) error {
+	parents := []itemKey{key}
+	marked, err := s.markParents(ctx, parents)
+	if err != nil {
+		return err
+	}
+
+	children, err := s.questionItemKeys(ctx, keyID(key, models.QuestionEntity))
+	if err != nil {
+		return s.abandonDelete(ctx, marked, err)
+	}
+
+	return s.deleteCascade(ctx, parents, children, marked)
+}
+
+// questionKey returns the key of a question, looking up the event it
+// belongs to. The question may be marked for deletion.
+func (s *DynamoStore) questionKey(ctx context.Context, questionID string) (itemKey, error) {
+	keys, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType AND pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(models.QuestionEntity)},
+			":pk":         &types.AttributeValueMemberS{Value: string(models.QuestionEntity) + "#" + questionID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query question from DynamoDB: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+// eventQuestionKeys returns the keys of all questions of an event
+func (s *DynamoStore) eventQuestionKeys(ctx context.Context, eventID string) ([]itemKey, error) {
+	keys, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EventIDIndex"),
+		KeyConditionExpression: aws.String("event_id = :eventID"),
+		FilterExpression:       aws.String("entity_type = :entityType"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":eventID":    &types.AttributeValueMemberS{Value: eventID},
+			":entityType": &types.AttributeValueMemberS{Value: string(models.QuestionEntity)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get questions to delete: %w", err)
+	}
+
+	return keys, nil
+}
+
+// questionItemKeys returns the keys of the options, ballots, vetoes and
+// ballot boxes of a question
+func (s *DynamoStore) questionItemKeys(ctx context.Context, questionID string) ([]itemKey, error) {
+	keys, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID": &types.AttributeValueMemberS{Value: questionID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options to delete: %w", err)
+	}
+
+	return keys, nil
+}
+
+// queryKeys runs a query through every page of results and returns the keys
+// of the items found
+func (s *DynamoStore) queryKeys(ctx context.Context, input *dynamodb.QueryInput) ([]itemKey, error) {
+	input.ProjectionExpression = aws.String("pk, sk")
+
+	items, err := s.queryAll(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]itemKey, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, itemKey{"pk": item["pk"], "sk": item["sk"]})
+	}
+	return keys, nil
+}
+
+// deleteCascade deletes parents and their children, once the parents are
+// marked, see markParents. If they all fit in one transaction they are
+// deleted in it, and if that fails the parents this delete marked are
+// unmarked; otherwise the children are deleted in batches and the parents
+// deleted last, in reverse order, so the first parent is the last to go.
+func (s *DynamoStore) deleteCascade(ctx context.Context, parents []itemKey, children []itemKey, marked []itemKey) error {
+	if len(parents)+len(children) <= maxTransactItems {
+		if err := s.transactDelete(ctx, append(children, parents...)); err != nil {
+			return s.abandonDelete(ctx, marked, err)
+		}
+		return nil
+	}
+
+	if err := s.batchDelete(ctx, children); err != nil {
+		return err
+	}
+
+	reversed := make([]itemKey, 0, len(parents))
+	for i := len(parents) - 1; i >= 0; i-- {
+		reversed = append(reversed, parents[i])
+	}
+	return s.batchDelete(ctx, reversed)
+}
+
+// transactDelete deletes the given items in a single transaction
+func (s *DynamoStore) transactDelete(ctx context.Context, keys []itemKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	items := make([]types.TransactWriteItem, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+			TableName: aws.String(s.table),
+			Key:       key,
+		}})
+	}
+
+	_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete items from DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// markParents marks the given items as being deleted, returning those this
+// call marked. Items already marked by an earlier delete are left to it, and
+// so are items that are already gone.
+func (s *DynamoStore) markParents(ctx context.Context, keys []itemKey) ([]itemKey, error) {
+	var marked []itemKey
+	for _, key := range keys {
+		ok, err := s.markDeleting(ctx, key)
+		if err != nil {
+			return nil, s.abandonDelete(ctx, marked, err)
+		}
+		if ok {
+			marked = append(marked, key)
+		}
+	}
+	return marked, nil
+}
+
+// markDeleting marks an item as being deleted, reporting false if it is
+// already marked or gone
+func (s *DynamoStore) markDeleting(ctx context.Context, key itemKey) (bool, error) {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 key,
+		UpdateExpression:    aws.String("SET deleting = :deleting"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_not_exists(deleting)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleting": &types.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark item for deletion in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// abandonDelete unmarks the items a delete that failed with err had marked,
+// before anything was deleted, and returns err. An item that cannot be
+// unmarked stays hidden until ResumeDeletes finishes deleting it.
+func (s *DynamoStore) abandonDelete(ctx context.Context, marked []itemKey, err error) error {
+	for _, key := range marked {
+		_, unmarkErr := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(s.table),
+			Key:                 key,
+			UpdateExpression:    aws.String("REMOVE deleting"),
+			ConditionExpression: aws.String("attribute_exists(pk)"),
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if unmarkErr != nil && !errors.As(unmarkErr, &conditionFailed) {
+			return fmt.Errorf("%w, and failed to unmark item: %v", err, unmarkErr)
+		}
+	}
+	return err
+}
+
+// parentCheck returns a transaction item that fails unless the parent of
+// the items written with it exists and is not being deleted, so nothing is
+// added beneath an item once a delete has marked it, see deleteCascade
+func (s *DynamoStore) parentCheck(parent itemKey) types.TransactWriteItem {
+	return types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+		TableName:           aws.String(s.table),
+		Key:                 parent,
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_not_exists(deleting)"),
+	}}
+}
+
+// putChild puts an item beneath parent, reporting false if the parent is
+// gone or being deleted, in which case nothing is written
+func (s *DynamoStore) putChild(ctx context.Context, parent itemKey, item map[string]types.AttributeValue) (bool, error) {
+	_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			s.parentCheck(parent),
+			{Put: &types.Put{
+				TableName: aws.String(s.table),
+				Item:      item,
+			}},
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 && aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// batchDelete deletes the given items in batches, in order, retrying the
+// items DynamoDB leaves unprocessed until ctx is done
+func (s *DynamoStore) batchDelete(ctx context.Context, keys []itemKey) error {
+	for start := 0; start < len(keys); start += maxBatchWriteItems {
+		end := min(start+maxBatchWriteItems, len(keys))
+
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+
+		pending := map[string][]types.WriteRequest{s.table: requests}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == maxBatchAttempts {
+				return fmt.Errorf("failed to delete items from DynamoDB after %d attempts", maxBatchAttempts)
+			}
+			if attempt > 0 {
+				if err := backOff(ctx, time.Duration(attempt)*100*time.Millisecond); err != nil {
+					return err
+				}
+			}
+
+			result, err := s.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete items from DynamoDB: %w", err)
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// backOff waits for the given time before a retry, or until ctx is done, in
+// which case it returns the context's error
+func backOff(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// markedQuery returns a query for the keys of items of the given type that
+// are marked for deletion
+func (s *DynamoStore) markedQuery(entity models.EntityType) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		FilterExpression:       aws.String("deleting = :deleting"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(entity)},
+			":deleting":   &types.AttributeValueMemberBOOL{Value: true},
+		},
+	}
+}
+
+// keyID returns the ID in the PK of an item of the given type
+func keyID(key itemKey, entity models.EntityType) string {
+	pk, _ := key["pk"].(*types.AttributeValueMemberS)
+	if pk == nil {
+		return ""
+	}
+	return strings.TrimPrefix(pk.Value, string(entity)+"#")
+}
@@ -0,0 +1,255 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// createBigEvent creates an event with a question holding more options than
+// fit in one transaction, returning the event and question IDs
+func createBigEvent(t *testing.T, store *DynamoStore) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	event := models.NewEvent("big-event", "Big")
+	if err := store.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	question := models.NewQuestion("big-question", event.ID, "Which?")
+	if err := store.AddQuestion(ctx, event.ID, question); err != nil {
+		t.Fatalf("failed to add question: %v", err)
+	}
+	for i := 0; i < maxTransactItems+20; i++ {
+		option := models.NewOption(fmt.Sprintf("option-%03d", i), question.ID, "Option")
+		if err := store.AddOption(ctx, question.ID, option); err != nil {
+			t.Fatalf("failed to add option: %v", err)
+		}
+	}
+
+	return event.ID, question.ID
+}
+
+// eventItemCount returns how many items carrying the event's ID are left,
+// whether they are marked for deletion or not
+func eventItemCount(t *testing.T, store *DynamoStore, eventID string) int {
+	t.Helper()
+
+	keys, err := store.queryKeys(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String(store.table),
+		IndexName:              aws.String("EventIDIndex"),
+		KeyConditionExpression: aws.String("event_id = :eventID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":eventID": &types.AttributeValueMemberS{Value: eventID},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to count items of event: %v", err)
+	}
+	return len(keys)
+}
+
+func TestDeleteEventCutShort(t *testing.T) {
+	store, client := newInstrumentedStore(t)
+	ctx := context.Background()
+	eventID, questionID := createBigEvent(t, store)
+
+	// Two batches of options go, then DynamoDB fails
+	client.fail("BatchWriteItem", 2)
+	if err := store.DeleteEvent(ctx, eventID); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteEvent returned %v, want the injected failure", err)
+	}
+	client.reset()
+
+	if left := eventItemCount(t, store, eventID); left == 0 {
+		t.Fatalf("nothing of the event is left to finish deleting")
+	}
+
+	// The marked event and question are hidden from every read
+	if event, err := store.GetEvent(ctx, eventID); err != nil || event != nil {
+		t.Errorf("GetEvent of a half deleted event = %v, %v, want nil", event, err)
+	}
+	if question, err := store.GetQuestion(ctx, questionID); err != nil || question != nil {
+		t.Errorf("GetQuestion of a half deleted question = %v, %v, want nil", question, err)
+	}
+	events, err := store.ListEvents(ctx)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("ListEvents returned %d events, want none", len(events))
+	}
+
+	if err := store.ResumeDeletes(ctx); err != nil {
+		t.Fatalf("failed to resume deletes: %v", err)
+	}
+	if left := eventItemCount(t, store, eventID); left != 0 {
+		t.Errorf("%d items of the event left after resuming", left)
+	}
+	for _, entity := range []models.EntityType{models.EventEntity, models.QuestionEntity} {
+		marked, err := store.queryKeys(ctx, store.markedQuery(entity))
+		if err != nil {
+			t.Fatalf("failed to find marked items: %v", err)
+		}
+		if len(marked) != 0 {
+			t.Errorf("%d marked %s items left after resuming", len(marked), entity)
+		}
+	}
+
+	if err := store.DeleteEvent(ctx, eventID); err != nil {
+		t.Errorf("deleting a deleted event failed: %v", err)
+	}
+}
+
+func TestDeleteEventDeletedTwice(t *testing.T) {
+	store, client := newInstrumentedStore(t)
+	ctx := context.Background()
+	eventID, _ := createBigEvent(t, store)
+
+	// The first delete dies after marking, and a second one takes over
+	client.fail("BatchWriteItem", 0)
+	if err := store.DeleteEvent(ctx, eventID); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteEvent returned %v, want the injected failure", err)
+	}
+	client.reset()
+
+	if err := store.DeleteEvent(ctx, eventID); err != nil {
+		t.Fatalf("deleting the event again failed: %v", err)
+	}
+	if left := eventItemCount(t, store, eventID); left != 0 {
+		t.Errorf("%d items of the event left after deleting it again", left)
+	}
+	if err := store.DeleteEvent(ctx, eventID); err != nil {
+		t.Errorf("deleting a deleted event failed: %v", err)
+	}
+}
+
+func TestDeleteEventTransactionFails(t *testing.T) {
+	store, client := newInstrumentedStore(t)
+	ctx := context.Background()
+
+	event := models.NewEvent("small-event", "Small")
+	if err := store.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	question := models.NewQuestion("small-question", event.ID, "Which?")
+	if err := store.AddQuestion(ctx, event.ID, question); err != nil {
+		t.Fatalf("failed to add question: %v", err)
+	}
+	option := models.NewOption("small-option", question.ID, "Option")
+	if err := store.AddOption(ctx, question.ID, option); err != nil {
+		t.Fatalf("failed to add option: %v", err)
+	}
+
+	// A small event goes in one transaction, so failing it leaves everything
+	client.fail("TransactWriteItems", 0)
+	if err := store.DeleteEvent(ctx, event.ID); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteEvent returned %v, want the injected failure", err)
+	}
+	client.reset()
+
+	stored, err := store.GetEvent(ctx, event.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("GetEvent after a failed delete = %v, %v, want the event", stored, err)
+	}
+	if len(stored.Questions) != 1 || len(stored.Questions[0].Options) != 1 {
+		t.Errorf("failed delete took part of the event with it: %+v", stored.Questions)
+	}
+
+	if err := store.DeleteEvent(ctx, event.ID); err != nil {
+		t.Fatalf("failed to delete event: %v", err)
+	}
+	if err := store.DeleteEvent(ctx, event.ID); err != nil {
+		t.Errorf("deleting a deleted event failed: %v", err)
+	}
+	if left := eventItemCount(t, store, event.ID); left != 0 {
+		t.Errorf("%d items of the event left after deleting it", left)
+	}
+}
+
+func TestDeleteQuestionCutShort(t *testing.T) {
+	store, client := newInstrumentedStore(t)
+	ctx := context.Background()
+	eventID, questionID := createBigEvent(t, store)
+
+	client.fail("BatchWriteItem", 1)
+	if err := store.DeleteQuestion(ctx, questionID); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteQuestion returned %v, want the injected failure", err)
+	}
+	client.reset()
+
+	event, err := store.GetEvent(ctx, eventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent = %v, %v, want the event", event, err)
+	}
+	if len(event.Questions) != 0 {
+		t.Errorf("half deleted question still shown on its event")
+	}
+
+	if err := store.ResumeDeletes(ctx); err != nil {
+		t.Fatalf("failed to resume deletes: %v", err)
+	}
+	options, err := store.GetOptionsByQuestionID(ctx, questionID)
+	if err != nil {
+		t.Fatalf("failed to get options: %v", err)
+	}
+	if len(options) != 0 {
+		t.Errorf("%d options left after resuming", len(options))
+	}
+	if event, err := store.GetEvent(ctx, eventID); err != nil || event == nil {
+		t.Errorf("GetEvent after deleting its question = %v, %v, want the event", event, err)
+	}
+}
+
+func TestNothingAddedBeneathMarkedItems(t *testing.T) {
+	store, _ := newInstrumentedStore(t)
+	ctx := context.Background()
+
+	event := models.NewEvent("marked-event", "Marked")
+	if err := store.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	question := models.NewQuestion("marked-question", event.ID, "Which?")
+	if err := store.AddQuestion(ctx, event.ID, question); err != nil {
+		t.Fatalf("failed to add question: %v", err)
+	}
+	option := models.NewOption("marked-option", question.ID, "Option")
+	if err := store.AddOption(ctx, question.ID, option); err != nil {
+		t.Fatalf("failed to add option: %v", err)
+	}
+
+	// A delete has marked the event and its question, but not yet listed
+	// what is beneath them
+	questionKey, err := store.questionKey(ctx, question.ID)
+	if err != nil || questionKey == nil {
+		t.Fatalf("questionKey = %v, %v", questionKey, err)
+	}
+	if _, err := store.markParents(ctx, []itemKey{eventKey(event.ID), questionKey}); err != nil {
+		t.Fatalf("failed to mark event: %v", err)
+	}
+
+	if err := store.AddQuestion(ctx, event.ID, models.NewQuestion("late-question", event.ID, "Which?")); err == nil {
+		t.Errorf("AddQuestion added a question to an event being deleted")
+	}
+	if err := store.AddOption(ctx, question.ID, models.NewOption("late-option", question.ID, "Late")); err == nil {
+		t.Errorf("AddOption added an option to a question being deleted")
+	}
+	ballot := models.NewBallot(question.ID, models.NewParticipant("late-participant", ""), option.ID)
+	if err := store.ReplaceBallots(ctx, question.ID, "late-participant", []models.Ballot{ballot}); err == nil {
+		t.Errorf("ReplaceBallots voted on a question being deleted")
+	}
+
+	if err := store.DeleteEvent(ctx, event.ID); err != nil {
+		t.Fatalf("failed to delete event: %v", err)
+	}
+	if left := eventItemCount(t, store, event.ID); left != 0 {
+		t.Errorf("%d items of the event left after deleting it", left)
+	}
+}
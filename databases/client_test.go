@@ -0,0 +1,137 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// errInjected is the error of a call failed by an instrumentedClient
+var errInjected = errors.New("injected failure")
+
+// instrumentedClient wraps a DynamoDB client, counting the calls made of each
+// operation and failing those of an operation in failAfter once it has let
+// that many through
+type instrumentedClient struct {
+	dynamoClient
+
+	mu        sync.Mutex
+	calls     map[string]int
+	failAfter map[string]int
+}
+
+func newInstrumentedClient(client dynamoClient) *instrumentedClient {
+	return &instrumentedClient{
+		dynamoClient: client,
+		calls:        make(map[string]int),
+		failAfter:    make(map[string]int),
+	}
+}
+
+// reset forgets the calls made so far and stops failing any
+func (c *instrumentedClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = make(map[string]int)
+	c.failAfter = make(map[string]int)
+}
+
+// fail makes calls of the operation fail once it has let n more through
+func (c *instrumentedClient) fail(operation string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failAfter[operation] = c.calls[operation] + n
+}
+
+// total returns how many calls were made of every operation together
+func (c *instrumentedClient) total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, n := range c.calls {
+		total += n
+	}
+	return total
+}
+
+// call counts a call of the operation, returning errInjected if it should fail
+func (c *instrumentedClient) call(operation string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls[operation]++
+	if limit, ok := c.failAfter[operation]; ok && c.calls[operation] > limit {
+		return errInjected
+	}
+	return nil
+}
+
+func (c *instrumentedClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := c.call("GetItem"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.GetItem(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := c.call("PutItem"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.PutItem(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := c.call("UpdateItem"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.UpdateItem(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := c.call("Query"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.Query(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) Scan(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := c.call("Scan"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.Scan(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := c.call("TransactWriteItems"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.TransactWriteItems(ctx, input, optFns...)
+}
+
+func (c *instrumentedClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := c.call("BatchWriteItem"); err != nil {
+		return nil, err
+	}
+	return c.dynamoClient.BatchWriteItem(ctx, input, optFns...)
+}
+
+// newInstrumentedStore returns a store on DynamoDB Local, see
+// NewDynamoTestStore, whose calls go through the returned client
+func newInstrumentedStore(t testing.TB) (*DynamoStore, *instrumentedClient) {
+	t.Helper()
+
+	var client *instrumentedClient
+	store := newDynamoTestStore(t, func(wrapped dynamoClient) dynamoClient {
+		client = newInstrumentedClient(wrapped)
+		return client
+	})
+	client.reset()
+
+	return store, client
+}
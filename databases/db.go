@@ -2,9 +2,13 @@ package databases
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 )
@@ -16,9 +20,23 @@ const (
 	DefaultRegion = "eu-west-2"
 )
 
-var (
-	DynamoClient *dynamodb.Client
-)
+// DynamoStore is the Store backed by a single DynamoDB table
+type DynamoStore struct {
+	client dynamoClient
+	table  string
+}
+
+// dynamoClient is the part of the DynamoDB API the store uses, which
+// *dynamodb.Client implements
+type dynamoClient interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
 
 // GetTableName returns the table name based on environment variables or defaults
 func GetTableName() string {
@@ -28,6 +46,12 @@ func GetTableName() string {
 	return TableName
 }
 
+// GetEndpoint returns the DynamoDB endpoint to use instead of AWS's, such as
+// DynamoDB Local's, or "" for AWS's own
+func GetEndpoint() string {
+	return os.Getenv("DYNAMODB_ENDPOINT")
+}
+
 // GetRegion returns the AWS region to use
 func GetRegion() string {
 	if region := os.Getenv("AWS_REGION"); region != "" {
@@ -36,8 +60,8 @@ func GetRegion() string {
 	return DefaultRegion
 }
 
-// InitDB initializes the DynamoDB client
-func InitDB() error {
+// NewDynamoStore initializes the DynamoDB client and returns a store using it
+func NewDynamoStore() (*DynamoStore, error) {
 	// Get region from environment or use default
 	region := GetRegion()
 
@@ -48,12 +72,47 @@ func InitDB() error {
 
 	if err != nil {
 		log.Printf("unable to load SDK config, %v", err)
-		return err
+		return nil, err
 	}
 
 	// Initialize DynamoDB client
-	DynamoClient = dynamodb.NewFromConfig(cfg)
-	log.Printf("DynamoDB client initialized, using table: %s in region: %s", GetTableName(), region)
+	var options []func(*dynamodb.Options)
+	if endpoint := GetEndpoint(); endpoint != "" {
+		options = append(options, func(o *dynamodb.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+		})
+	}
+	store := &DynamoStore{
+		client: dynamodb.NewFromConfig(cfg, options...),
+		table:  GetTableName(),
+	}
+	log.Printf("DynamoDB client initialized, using table: %s in region: %s", store.table, region)
+
+	if err := store.migrate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+const (
+	// maxTransactItems is the most items DynamoDB accepts in one transaction
+	maxTransactItems = 100
+	// maxBallotAttempts is how often a ballot change is retried when it races
+	// another change by the same participant
+	maxBallotAttempts = 3
+
+	ballotBoxSK     = "BALLOTBOX"
+	ballotBoxEntity = models.EntityType("BALLOT_BOX")
+)
+
+var errBallotConflict = errors.New("ballots were changed concurrently")
 
-	return nil
+// ballotBox guards a participant's ballots on one question, see ReplaceBallots
+type ballotBox struct {
+	models.DynamoItem
+	QuestionID string            `dynamodbav:"question_id"`
+	EventID    string            `dynamodbav:"event_id,omitempty"`
+	Revision   int               `dynamodbav:"revision"`
+	EntityType models.EntityType `dynamodbav:"entity_type"`
 }
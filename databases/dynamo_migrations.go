@@ -0,0 +1,251 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoMigrationPK is the partition recording which DynamoDB migrations have
+// been applied, one item per migration
+const dynamoMigrationPK = "MIGRATION"
+
+// dynamoMigration is a single versioned change to the items in the DynamoDB
+// table. The table has no schema beyond its keys and indexes, so migrations
+// rewrite existing items instead. Each must be safe to run more than once, as
+// several replicas starting together may all apply it.
+type dynamoMigration struct {
+	version int
+	name    string
+	apply   func(s *DynamoStore, ctx context.Context) error
+}
+
+// dynamoMigrations are applied in order on startup. Add new ones to the end.
+var dynamoMigrations = []dynamoMigration{
+	{version: 1, name: "0001_add_event_ids", apply: (*DynamoStore).addEventIDs},
+	{version: 2, name: "0002_turn_vote_counts_into_ballots", apply: (*DynamoStore).turnVoteCountsIntoBallots},
+	{version: 3, name: "0003_add_event_ids_to_events", apply: (*DynamoStore).addEventIDsToEvents},
+}
+
+// migrate applies the migrations that have not been applied to the table yet
+func (s *DynamoStore) migrate(ctx context.Context) error {
+	for _, m := range dynamoMigrations {
+		key := map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: dynamoMigrationPK},
+			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%04d", dynamoMigrationPK, m.version)},
+		}
+
+		result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.table),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		if result.Item != nil {
+			continue
+		}
+
+		if err := m.apply(s, ctx); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+		}
+
+		key["name"] = &types.AttributeValueMemberS{Value: m.name}
+		key["applied_at"] = &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)}
+		_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(s.table),
+			Item:      key,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+		log.Printf("Applied migration %s", m.name)
+	}
+
+	return nil
+}
+
+// addEventIDs gives the options, ballots, vetoes and ballot boxes stored
+// before they carried an event_id the ID of their question's event, putting
+// them in the event's item collection, see GetQuestionsByEventID
+func (s *DynamoStore) addEventIDs(ctx context.Context) error {
+	questions, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(models.QuestionEntity)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query questions: %w", err)
+	}
+
+	eventIDs := make(map[string]string, len(questions))
+	for _, key := range questions {
+		sk, _ := key["sk"].(*types.AttributeValueMemberS)
+		if sk == nil {
+			continue
+		}
+		eventIDs[keyID(key, models.QuestionEntity)] = strings.TrimPrefix(sk.Value, string(models.EventEntity)+"#")
+	}
+
+	for _, entity := range []models.EntityType{models.OptionEntity, models.BallotEntity, models.VetoEntity, ballotBoxEntity} {
+		items, err := s.queryAll(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(s.table),
+			IndexName:              aws.String("EntityTypeIndex"),
+			KeyConditionExpression: aws.String("entity_type = :entityType"),
+			FilterExpression:       aws.String("attribute_not_exists(event_id)"),
+			ProjectionExpression:   aws.String("pk, sk, question_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":entityType": &types.AttributeValueMemberS{Value: string(entity)},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query %s items: %w", entity, err)
+		}
+
+		for _, item := range items {
+			questionID, _ := item["question_id"].(*types.AttributeValueMemberS)
+			if questionID == nil {
+				continue
+			}
+			// Items left behind by a deleted question have no event to join
+			eventID, ok := eventIDs[questionID.Value]
+			if !ok {
+				continue
+			}
+
+			_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:           aws.String(s.table),
+				Key:                 itemKey{"pk": item["pk"], "sk": item["sk"]},
+				UpdateExpression:    aws.String("SET event_id = :eventID"),
+				ConditionExpression: aws.String("attribute_exists(pk)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":eventID": &types.AttributeValueMemberS{Value: eventID},
+				},
+			})
+			var conditionFailed *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &conditionFailed) {
+				return fmt.Errorf("failed to add event ID to %s item: %w", entity, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// turnVoteCountsIntoBallots replaces the votes counted on options from before
+// votes were ballots with a ballot for every vote, each cast by an anonymous
+// participant of its own, legacy-<option id>-<n>, so the counts carry over.
+// Nobody can change or withdraw those votes, as nobody holds their
+// participants' IDs.
+func (s *DynamoStore) turnVoteCountsIntoBallots(ctx context.Context) error {
+	options, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		FilterExpression:       aws.String("attribute_exists(votes)"),
+		ProjectionExpression:   aws.String("pk, sk, id, question_id, event_id, votes"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(models.OptionEntity)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query options: %w", err)
+	}
+
+	for _, item := range options {
+		var option struct {
+			ID         string `dynamodbav:"id"`
+			QuestionID string `dynamodbav:"question_id"`
+			EventID    string `dynamodbav:"event_id"`
+			Votes      int    `dynamodbav:"votes"`
+		}
+		if err := attributevalue.UnmarshalMap(item, &option); err != nil {
+			return fmt.Errorf("failed to unmarshal option: %w", err)
+		}
+
+		// Options left behind by a deleted question have nothing to count for
+		for n := 1; n <= option.Votes && option.EventID != ""; n++ {
+			participant := models.NewParticipant("legacy-"+option.ID+"-"+strconv.Itoa(n), "")
+			ballot, err := attributevalue.MarshalMap(models.NewBallot(option.QuestionID, participant, option.ID))
+			if err != nil {
+				return fmt.Errorf("failed to marshal ballot: %w", err)
+			}
+			ballot["event_id"] = &types.AttributeValueMemberS{Value: option.EventID}
+
+			_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(s.table),
+				Item:      ballot,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to put ballot for option %s: %w", option.ID, err)
+			}
+		}
+
+		_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(s.table),
+			Key:                 itemKey{"pk": item["pk"], "sk": item["sk"]},
+			UpdateExpression:    aws.String("REMOVE votes"),
+			ConditionExpression: aws.String("attribute_exists(pk)"),
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if err != nil && !errors.As(err, &conditionFailed) {
+			return fmt.Errorf("failed to remove vote count of option %s: %w", option.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// addEventIDsToEvents gives the events stored before they carried their own
+// event_id one, putting each in its item collection, see GetEvent
+func (s *DynamoStore) addEventIDsToEvents(ctx context.Context) error {
+	events, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		FilterExpression:       aws.String("attribute_not_exists(event_id)"),
+		ProjectionExpression:   aws.String("pk, sk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(models.EventEntity)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+
+	for _, item := range events {
+		key := itemKey{"pk": item["pk"], "sk": item["sk"]}
+		eventID := keyID(key, models.EventEntity)
+
+		_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(s.table),
+			Key:                 key,
+			UpdateExpression:    aws.String("SET event_id = :eventID"),
+			ConditionExpression: aws.String("attribute_exists(pk)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":eventID": &types.AttributeValueMemberS{Value: eventID},
+			},
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if err != nil && !errors.As(err, &conditionFailed) {
+			return fmt.Errorf("failed to add event ID to event %s: %w", eventID, err)
+		}
+	}
+
+	return nil
+}
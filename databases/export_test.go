@@ -0,0 +1,106 @@
+package databases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// NewDynamoTestStore returns a DynamoStore using a table of its own in the
+// DynamoDB Local at DYNAMODB_ENDPOINT, deleted once the test is done. The
+// test is skipped if DYNAMODB_ENDPOINT is not set.
+func NewDynamoTestStore(t testing.TB) *DynamoStore {
+	return newDynamoTestStore(t, nil)
+}
+
+// newDynamoTestStore is NewDynamoTestStore with the store's client wrapped
+// by wrap, if given, once the table is ready
+func newDynamoTestStore(t testing.TB, wrap func(dynamoClient) dynamoClient) *DynamoStore {
+	t.Helper()
+
+	endpoint := GetEndpoint()
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT is not set")
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(GetRegion()),
+		config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local"}, nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to load SDK config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+
+	suffix, err := utils.GenerateID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := "planzoco-test-" + suffix
+
+	index := func(name string, hash string) types.GlobalSecondaryIndex {
+		return types.GlobalSecondaryIndex{
+			IndexName: aws.String(name),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String(hash), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}
+	}
+	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("entity_type"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("event_id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("question_id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("closes_at"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			index("EntityTypeIndex", "entity_type"),
+			index("EventIDIndex", "event_id"),
+			index("QuestionIDIndex", "question_id"),
+			index("ClosesAtIndex", "closes_at"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create table %s: %v", table, err)
+	}
+	t.Cleanup(func() {
+		client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: aws.String(table)})
+	})
+
+	err = dynamodb.NewTableExistsWaiter(client).Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, time.Minute)
+	if err != nil {
+		t.Fatalf("table %s never became ready: %v", table, err)
+	}
+
+	store := &DynamoStore{client: client, table: table}
+	if wrap != nil {
+		store.client = wrap(client)
+	}
+	if err := store.migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
@@ -0,0 +1,600 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+)
+
+// MemoryStore is a Store that keeps everything in process memory. It is meant
+// for local development and tests, and loses all data when the process exits.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	events    map[string]models.Event
+	questions map[string]models.Question
+	options   map[string]models.Option
+
+	participants map[string]models.Participant
+	ballots      map[string][]models.Ballot // question ID -> ballots in the order they were cast
+	vetoes       map[string][]models.Veto   // question ID -> vetoes in the order they were cast
+
+	// Insertion order, so listings are stable between calls
+	eventIDs    []string
+	questionIDs map[string][]string // event ID -> question IDs
+	optionIDs   map[string][]string // question ID -> option IDs
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		events:       make(map[string]models.Event),
+		questions:    make(map[string]models.Question),
+		options:      make(map[string]models.Option),
+		participants: make(map[string]models.Participant),
+		ballots:      make(map[string][]models.Ballot),
+		vetoes:       make(map[string][]models.Veto),
+		questionIDs:  make(map[string][]string),
+		optionIDs:    make(map[string][]string),
+	}
+}
+
+// Event Operations
+
+// CreateEvent stores a new event
+func (s *MemoryStore) CreateEvent(ctx context.Context, event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.putEvent(event)
+	return nil
+}
+
+// GetEvent retrieves an event by ID along with its questions and options
+func (s *MemoryStore) GetEvent(ctx context.Context, eventID string) (*models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return nil, nil
+	}
+
+	event.Questions = s.questionsForEvent(eventID)
+	return &event, nil
+}
+
+// UpdateEvent updates the name, moderation and close time of an existing event
+func (s *MemoryStore) UpdateEvent(ctx context.Context, event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existingEvent, ok := s.events[event.ID]
+	if !ok {
+		return fmt.Errorf("event not found for update: %s", event.ID)
+	}
+	if event.Version != existingEvent.Version {
+		return ErrConflict
+	}
+
+	existingEvent.Name = event.Name
+	existingEvent.ModerateOptions = event.ModerateOptions
+	existingEvent.ClosesAt = event.ClosesAt
+	existingEvent.Version++
+	s.events[event.ID] = existingEvent
+	return nil
+}
+
+// SetAdminToken gives an event without an admin token the token, reporting
+// false if it already had one
+func (s *MemoryStore) SetAdminToken(ctx context.Context, eventID string, token string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return false, fmt.Errorf("event not found for admin token: %s", eventID)
+	}
+	if event.AdminToken != "" {
+		return false, nil
+	}
+
+	event.AdminToken = token
+	s.events[eventID] = event
+	return true, nil
+}
+
+// DeleteEvent deletes an event and all associated questions and options
+func (s *MemoryStore) DeleteEvent(ctx context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, questionID := range s.questionIDs[eventID] {
+		s.deleteQuestion(questionID)
+	}
+	delete(s.questionIDs, eventID)
+
+	if _, ok := s.events[eventID]; ok {
+		delete(s.events, eventID)
+		s.eventIDs = removeID(s.eventIDs, eventID)
+	}
+
+	return nil
+}
+
+// ListEvents retrieves all events, without their questions
+func (s *MemoryStore) ListEvents(ctx context.Context) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]models.Event, 0, len(s.eventIDs))
+	for _, eventID := range s.eventIDs {
+		events = append(events, s.events[eventID])
+	}
+
+	return events, nil
+}
+
+// ListEventsPage retrieves a page of events in the order they were created,
+// without their questions
+func (s *MemoryStore) ListEventsPage(ctx context.Context, limit int, cursor string) ([]models.Event, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end, next, err := PageOf(s.eventIDs, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := make([]models.Event, 0, end-start)
+	for _, eventID := range s.eventIDs[start:end] {
+		events = append(events, s.events[eventID])
+	}
+
+	return events, next, nil
+}
+
+// Question Operations
+
+// AddQuestion stores a new question for an event
+func (s *MemoryStore) AddQuestion(ctx context.Context, eventID string, question models.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if question.PK == "" || question.SK == "" {
+		keyed := models.NewQuestion(question.ID, eventID, question.Text)
+		keyed.Type = question.Type
+		keyed.MaxSelections = question.MaxSelections
+		keyed.ScaleMin, keyed.ScaleMax = question.ScaleMin, question.ScaleMax
+		keyed.WinBy = question.WinBy
+		keyed.ClosesAt = question.ClosesAt
+		keyed.TieBreak = question.TieBreak
+		keyed.MinParticipants = question.MinParticipants
+		keyed.Majority, keyed.MajorityPercent = question.Majority, question.MajorityPercent
+		keyed.VetoLimit = question.VetoLimit
+		keyed.HideResults, keyed.ResultsRevealed = question.HideResults, question.ResultsRevealed
+		keyed.Locked = question.Locked
+		question = keyed
+	}
+	s.putQuestion(question)
+	return nil
+}
+
+// GetQuestion retrieves a question by ID along with its options
+func (s *MemoryStore) GetQuestion(ctx context.Context, questionID string) (*models.Question, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	question, ok := s.questions[questionID]
+	if !ok {
+		return nil, nil
+	}
+
+	question.Options = s.optionsForQuestion(questionID)
+	question.Ballots = s.ballotsForQuestion(questionID)
+	question.Vetoes = s.vetoesForQuestion(questionID)
+	return &question, nil
+}
+
+// GetQuestionWithEvent retrieves a question with its associated event
+func (s *MemoryStore) GetQuestionWithEvent(ctx context.Context, questionID string) (*models.Question, *models.Event, error) {
+	question, err := s.GetQuestion(ctx, questionID)
+	if err != nil || question == nil {
+		return question, nil, err
+	}
+
+	event, err := s.GetEvent(ctx, question.EventID)
+	if err != nil {
+		return question, nil, err
+	}
+
+	return question, event, nil
+}
+
+// UpdateQuestion replaces an existing question, keeping its decision
+func (s *MemoryStore) UpdateQuestion(ctx context.Context, question models.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existingQuestion, ok := s.questions[question.ID]
+	if !ok {
+		return fmt.Errorf("question not found for update: %s", question.ID)
+	}
+	if question.Version != existingQuestion.Version {
+		return ErrConflict
+	}
+
+	// The event, type and scale are fixed once the question exists, and the
+	// decision is only set by CloseQuestion, whatever the caller passes
+	keyed := models.NewQuestion(question.ID, existingQuestion.EventID, question.Text)
+	keyed.Type = existingQuestion.Type
+	keyed.MaxSelections = question.MaxSelections
+	keyed.ScaleMin, keyed.ScaleMax = existingQuestion.ScaleMin, existingQuestion.ScaleMax
+	keyed.WinBy = question.WinBy
+	keyed.ClosesAt = question.ClosesAt
+	keyed.TieBreak = question.TieBreak
+	keyed.MinParticipants = question.MinParticipants
+	keyed.Majority, keyed.MajorityPercent = question.Majority, question.MajorityPercent
+	keyed.VetoLimit = question.VetoLimit
+	keyed.HideResults, keyed.ResultsRevealed = question.HideResults, question.ResultsRevealed
+	keyed.Locked = question.Locked
+	keyed.Decision = existingQuestion.Decision
+	keyed.Version = existingQuestion.Version + 1
+	s.putQuestion(keyed)
+	return nil
+}
+
+// CloseQuestion stores the decision of a question unless it already has one
+func (s *MemoryStore) CloseQuestion(ctx context.Context, questionID string, decision models.Decision) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[questionID]
+	if !ok {
+		return false, fmt.Errorf("question not found for closing: %s", questionID)
+	}
+	if question.Decided() {
+		return false, nil
+	}
+
+	question.Decision = &decision
+	question.Version++
+	s.questions[questionID] = question
+	return true, nil
+}
+
+// UpdateDecision replaces the decision of a decided question
+func (s *MemoryStore) UpdateDecision(ctx context.Context, questionID string, decision models.Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[questionID]
+	if !ok || !question.Decided() {
+		return fmt.Errorf("decided question not found: %s", questionID)
+	}
+
+	question.Decision = &decision
+	question.Version++
+	s.questions[questionID] = question
+	return nil
+}
+
+// DeleteQuestion deletes a question and all its options
+func (s *MemoryStore) DeleteQuestion(ctx context.Context, questionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[questionID]
+	if !ok {
+		return fmt.Errorf("question not found for deletion: %s", questionID)
+	}
+
+	s.deleteQuestion(questionID)
+	s.questionIDs[question.EventID] = removeID(s.questionIDs[question.EventID], questionID)
+	return nil
+}
+
+// GetQuestionsByEventID retrieves all questions for a given event ID
+func (s *MemoryStore) GetQuestionsByEventID(ctx context.Context, eventID string) ([]models.Question, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.questionsForEvent(eventID), nil
+}
+
+// ListDueQuestionIDs retrieves the IDs of the undecided questions whose
+// close time, or their event's, has passed
+func (s *MemoryStore) ListDueQuestionIDs(ctx context.Context, now time.Time) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var questionIDs []string
+	for _, eventID := range s.eventIDs {
+		event := s.events[eventID]
+		for _, questionID := range s.questionIDs[eventID] {
+			if s.questions[questionID].DueToClose(&event, now) {
+				questionIDs = append(questionIDs, questionID)
+			}
+		}
+	}
+	return questionIDs, nil
+}
+
+// Option Operations
+
+// AddOption stores a new option for a question
+func (s *MemoryStore) AddOption(ctx context.Context, questionID string, option models.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if option.PK == "" || option.SK == "" {
+		keyed := models.NewOption(option.ID, questionID, option.Text)
+		keyed.Slot = option.Slot
+		keyed.Pending = option.Pending
+		keyed.CreatedAt = option.CreatedAt
+		option = keyed
+	}
+	option.CreatedAt = createdAt(option.CreatedAt)
+	s.putOption(option)
+	return nil
+}
+
+// GetOption retrieves an option by ID
+func (s *MemoryStore) GetOption(ctx context.Context, optionID string) (*models.Option, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	option, ok := s.options[optionID]
+	if !ok {
+		return nil, nil
+	}
+
+	options := []models.Option{option}
+	models.CountVotes(options, s.ballots[option.QuestionID])
+	models.CountVetoes(options, s.vetoes[option.QuestionID])
+	return &options[0], nil
+}
+
+// UpdateOption updates the text, slot and approval of an existing option,
+// leaving its votes alone
+func (s *MemoryStore) UpdateOption(ctx context.Context, option models.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existingOption, ok := s.options[option.ID]
+	if !ok {
+		return fmt.Errorf("option not found for update: %s", option.ID)
+	}
+	if option.Version != existingOption.Version {
+		return ErrConflict
+	}
+
+	existingOption.Text = option.Text
+	existingOption.Slot = option.Slot
+	existingOption.Pending = option.Pending
+	existingOption.Version++
+	s.options[option.ID] = existingOption
+	return nil
+}
+
+// DeleteOption deletes an option by ID
+func (s *MemoryStore) DeleteOption(ctx context.Context, optionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	option, ok := s.options[optionID]
+	if !ok {
+		return fmt.Errorf("option not found for deletion: %s", optionID)
+	}
+
+	delete(s.options, optionID)
+	s.optionIDs[option.QuestionID] = removeID(s.optionIDs[option.QuestionID], optionID)
+
+	// Ballots for the option go with it
+	var ballots []models.Ballot
+	for _, ballot := range s.ballots[option.QuestionID] {
+		if ballot.OptionID != optionID {
+			ballots = append(ballots, ballot)
+		}
+	}
+	s.ballots[option.QuestionID] = ballots
+
+	var vetoes []models.Veto
+	for _, veto := range s.vetoes[option.QuestionID] {
+		if veto.OptionID != optionID {
+			vetoes = append(vetoes, veto)
+		}
+	}
+	s.vetoes[option.QuestionID] = vetoes
+	return nil
+}
+
+// GetOptionsByQuestionID retrieves all options for a given question ID
+func (s *MemoryStore) GetOptionsByQuestionID(ctx context.Context, questionID string) ([]models.Option, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.optionsForQuestion(questionID), nil
+}
+
+// Participant Operations
+
+// SaveParticipant creates or replaces a participant
+func (s *MemoryStore) SaveParticipant(ctx context.Context, participant models.Participant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if participant.PK == "" || participant.SK == "" {
+		participant = models.NewParticipant(participant.ID, participant.Name)
+	}
+	s.participants[participant.ID] = participant
+	return nil
+}
+
+// GetParticipant retrieves a participant by ID
+func (s *MemoryStore) GetParticipant(ctx context.Context, participantID string) (*models.Participant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	participant, ok := s.participants[participantID]
+	if !ok {
+		return nil, nil
+	}
+	return &participant, nil
+}
+
+// Ballot Operations
+
+// GetBallotsByQuestionID retrieves every ballot cast on a question
+func (s *MemoryStore) GetBallotsByQuestionID(ctx context.Context, questionID string) ([]models.Ballot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ballotsForQuestion(questionID), nil
+}
+
+// ReplaceBallots swaps all of a participant's ballots on a question for the given ones
+func (s *MemoryStore) ReplaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error {
+	if err := checkBallots(questionID, participantID, ballots); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.questions[questionID]; !ok {
+		return fmt.Errorf("question not found: %s", questionID)
+	}
+	for _, ballot := range ballots {
+		if option, ok := s.options[ballot.OptionID]; !ok || option.QuestionID != questionID {
+			return fmt.Errorf("option not found: %s", ballot.OptionID)
+		}
+	}
+
+	var kept []models.Ballot
+	for _, ballot := range s.ballots[questionID] {
+		if ballot.ParticipantID != participantID {
+			kept = append(kept, ballot)
+		}
+	}
+	s.ballots[questionID] = append(kept, ballots...)
+	return nil
+}
+
+// Veto Operations
+
+// ReplaceVetoes swaps all of a participant's vetoes on a question for the given ones
+func (s *MemoryStore) ReplaceVetoes(ctx context.Context, questionID string, participantID string, vetoes []models.Veto) error {
+	if err := checkVetoes(questionID, participantID, vetoes); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.questions[questionID]; !ok {
+		return fmt.Errorf("question not found: %s", questionID)
+	}
+	for _, veto := range vetoes {
+		if option, ok := s.options[veto.OptionID]; !ok || option.QuestionID != questionID {
+			return fmt.Errorf("option not found: %s", veto.OptionID)
+		}
+	}
+
+	var kept []models.Veto
+	for _, veto := range s.vetoes[questionID] {
+		if veto.ParticipantID != participantID {
+			kept = append(kept, veto)
+		}
+	}
+	s.vetoes[questionID] = append(kept, vetoes...)
+	return nil
+}
+
+// The helpers below expect the caller to hold s.mu
+
+func (s *MemoryStore) putEvent(event models.Event) {
+	if event.PK == "" || event.SK == "" {
+		keyed := models.NewEvent(event.ID, event.Name)
+		keyed.AdminToken = event.AdminToken
+		keyed.ModerateOptions = event.ModerateOptions
+		keyed.ClosesAt = event.ClosesAt
+		event = keyed
+	}
+	// Questions are stored separately, like in DynamoDB
+	event.Questions = nil
+
+	if _, ok := s.events[event.ID]; !ok {
+		s.eventIDs = append(s.eventIDs, event.ID)
+	}
+	s.events[event.ID] = event
+}
+
+func (s *MemoryStore) putQuestion(question models.Question) {
+	// Options are stored separately, like in DynamoDB
+	question.Options = nil
+
+	if _, ok := s.questions[question.ID]; !ok {
+		s.questionIDs[question.EventID] = append(s.questionIDs[question.EventID], question.ID)
+	}
+	s.questions[question.ID] = question
+}
+
+func (s *MemoryStore) putOption(option models.Option) {
+	if _, ok := s.options[option.ID]; !ok {
+		s.optionIDs[option.QuestionID] = append(s.optionIDs[option.QuestionID], option.ID)
+	}
+	s.options[option.ID] = option
+}
+
+func (s *MemoryStore) deleteQuestion(questionID string) {
+	for _, optionID := range s.optionIDs[questionID] {
+		delete(s.options, optionID)
+	}
+	delete(s.optionIDs, questionID)
+	delete(s.ballots, questionID)
+	delete(s.vetoes, questionID)
+	delete(s.questions, questionID)
+}
+
+func (s *MemoryStore) questionsForEvent(eventID string) []models.Question {
+	var questions []models.Question
+	for _, questionID := range s.questionIDs[eventID] {
+		question := s.questions[questionID]
+		question.Options = s.optionsForQuestion(questionID)
+		question.Ballots = s.ballotsForQuestion(questionID)
+		question.Vetoes = s.vetoesForQuestion(questionID)
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+func (s *MemoryStore) optionsForQuestion(questionID string) []models.Option {
+	var options []models.Option
+	for _, optionID := range s.optionIDs[questionID] {
+		options = append(options, s.options[optionID])
+	}
+	models.CountVotes(options, s.ballots[questionID])
+	models.CountVetoes(options, s.vetoes[questionID])
+	return options
+}
+
+func (s *MemoryStore) ballotsForQuestion(questionID string) []models.Ballot {
+	return append([]models.Ballot(nil), s.ballots[questionID]...)
+}
+
+func (s *MemoryStore) vetoesForQuestion(questionID string) []models.Veto {
+	return append([]models.Veto(nil), s.vetoes[questionID]...)
+}
+
+func removeID(ids []string, id string) []string {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
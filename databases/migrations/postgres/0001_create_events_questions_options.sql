@@ -0,0 +1,24 @@
+CREATE TABLE events (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE questions (
+    id         TEXT PRIMARY KEY,
+    event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    text       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX questions_event_id ON questions (event_id);
+
+CREATE TABLE options (
+    id          TEXT PRIMARY KEY,
+    question_id TEXT NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    text        TEXT NOT NULL,
+    votes       INTEGER NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX options_question_id ON options (question_id);
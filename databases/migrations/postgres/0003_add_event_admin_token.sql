@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN admin_token TEXT NOT NULL DEFAULT '';
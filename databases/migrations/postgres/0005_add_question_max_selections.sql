@@ -0,0 +1 @@
+ALTER TABLE questions ADD COLUMN max_selections INTEGER NOT NULL DEFAULT 0;
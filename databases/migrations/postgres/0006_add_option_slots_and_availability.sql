@@ -0,0 +1,4 @@
+ALTER TABLE options ADD COLUMN slot_start TIMESTAMPTZ;
+ALTER TABLE options ADD COLUMN slot_end TIMESTAMPTZ;
+ALTER TABLE options ADD COLUMN slot_time_zone TEXT NOT NULL DEFAULT '';
+ALTER TABLE ballots ADD COLUMN availability TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE questions ADD COLUMN scale_min INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE questions ADD COLUMN scale_max INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE questions ADD COLUMN win_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE ballots ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN closes_at TIMESTAMPTZ;
+ALTER TABLE questions ADD COLUMN closes_at TIMESTAMPTZ;
+ALTER TABLE questions ADD COLUMN decision TEXT NOT NULL DEFAULT '';
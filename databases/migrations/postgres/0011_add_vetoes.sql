@@ -0,0 +1,12 @@
+ALTER TABLE questions ADD COLUMN veto_limit INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE vetoes (
+    question_id      TEXT NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    option_id        TEXT NOT NULL REFERENCES options (id) ON DELETE CASCADE,
+    participant_id   TEXT NOT NULL REFERENCES participants (id) ON DELETE CASCADE,
+    participant_name TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (question_id, participant_id, option_id)
+);
+
+CREATE INDEX vetoes_option_id ON vetoes (option_id);
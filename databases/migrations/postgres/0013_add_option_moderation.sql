@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN moderate_options BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE questions ADD COLUMN locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE options ADD COLUMN pending BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE questions ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE options ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
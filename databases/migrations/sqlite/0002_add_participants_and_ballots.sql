@@ -0,0 +1,38 @@
+CREATE TABLE participants (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE ballots (
+    question_id      TEXT NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    option_id        TEXT NOT NULL REFERENCES options (id) ON DELETE CASCADE,
+    participant_id   TEXT NOT NULL REFERENCES participants (id) ON DELETE CASCADE,
+    participant_name TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMP NOT NULL,
+    PRIMARY KEY (question_id, participant_id, option_id)
+);
+
+CREATE INDEX ballots_option_id ON ballots (option_id);
+
+-- Votes are counted from the ballots from now on. Every vote counted so far
+-- becomes the ballot of an anonymous participant of its own, named
+-- legacy-<option id>-<n>, so the counts carry over. Nobody can change or
+-- withdraw those votes, as nobody holds their participants' IDs.
+CREATE TEMPORARY TABLE legacy_votes AS
+WITH RECURSIVE counted (option_id, question_id, n) AS (
+    SELECT id, question_id, votes FROM options WHERE votes > 0
+    UNION ALL
+    SELECT option_id, question_id, n - 1 FROM counted WHERE n > 1
+)
+SELECT option_id, question_id, 'legacy-' || option_id || '-' || n AS participant_id FROM counted;
+
+INSERT INTO participants (id, name, created_at)
+SELECT participant_id, '', CURRENT_TIMESTAMP FROM legacy_votes;
+
+INSERT INTO ballots (question_id, option_id, participant_id, participant_name, created_at)
+SELECT question_id, option_id, participant_id, '', CURRENT_TIMESTAMP FROM legacy_votes;
+
+DROP TABLE legacy_votes;
+
+ALTER TABLE options DROP COLUMN votes;
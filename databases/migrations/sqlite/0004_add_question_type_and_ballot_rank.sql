@@ -0,0 +1,2 @@
+ALTER TABLE questions ADD COLUMN type TEXT NOT NULL DEFAULT 'plurality';
+ALTER TABLE ballots ADD COLUMN rank INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE questions ADD COLUMN tie_break TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE questions ADD COLUMN min_participants INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE questions ADD COLUMN majority TEXT NOT NULL DEFAULT '';
+ALTER TABLE questions ADD COLUMN majority_percent INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE questions ADD COLUMN hide_results BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE questions ADD COLUMN results_revealed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,170 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TestMigrationKeepsVotes makes sure the votes counted before there were
+// ballots survive the move to ballots
+func TestMigrationKeepsVotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "planzoco.db")
+
+	// A database from before ballots, with only the first migration applied
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(BackendSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for _, statement := range []string{
+		migrations[0].sql,
+		"CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMP NOT NULL)",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, insert := range []struct {
+		query string
+		args  []any
+	}{
+		{"INSERT INTO schema_migrations (version, applied_at) VALUES (1, ?)", []any{now}},
+		{"INSERT INTO events (id, name, created_at) VALUES ('e1', 'Dinner', ?)", []any{now}},
+		{"INSERT INTO questions (id, event_id, text, created_at) VALUES ('q1', 'e1', 'Where?', ?)", []any{now}},
+		{"INSERT INTO options (id, question_id, text, votes, created_at) VALUES ('o1', 'q1', 'Thai', 3, ?)", []any{now}},
+		{"INSERT INTO options (id, question_id, text, votes, created_at) VALUES ('o2', 'q1', 'Pizza', 0, ?)", []any{now.Add(time.Second)}},
+		{"INSERT INTO options (id, question_id, text, votes, created_at) VALUES ('o3', 'q1', 'Curry', 1, ?)", []any{now.Add(2 * time.Second)}},
+	} {
+		if _, err := db.Exec(insert.query, insert.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	options, err := store.GetOptionsByQuestionID(context.Background(), "q1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"o1": 3, "o2": 0, "o3": 1}
+	if len(options) != len(want) {
+		t.Fatalf("got %d options, want %d", len(options), len(want))
+	}
+	for _, option := range options {
+		if option.Votes != want[option.ID] {
+			t.Errorf("option %s has %d votes, want %d", option.ID, option.Votes, want[option.ID])
+		}
+	}
+}
+
+// TestDynamoMigrationsAddEventIDs takes an event back to before its items
+// carried their event's ID, then makes sure migrating puts it together again
+func TestDynamoMigrationsAddEventIDs(t *testing.T) {
+	store := NewDynamoTestStore(t)
+	ctx := context.Background()
+	eventID := createVotedEvent(t, store)
+
+	// Questions always carried their event's ID; nothing else did
+	items, err := store.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(store.table),
+		IndexName:              aws.String("EventIDIndex"),
+		KeyConditionExpression: aws.String("event_id = :eventID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":eventID": &types.AttributeValueMemberS{Value: eventID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if entityType(item) == models.QuestionEntity {
+			continue
+		}
+		_, err := store.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:        aws.String(store.table),
+			Key:              itemKey{"pk": item["pk"], "sk": item["sk"]},
+			UpdateExpression: aws.String("REMOVE event_id"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Forget the migrations that add the IDs
+	var applied []itemKey
+	for _, version := range []int{1, 3} {
+		sk := fmt.Sprintf("%s#%04d", dynamoMigrationPK, version)
+		applied = append(applied, itemKey{
+			"pk": &types.AttributeValueMemberS{Value: dynamoMigrationPK},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		})
+	}
+	if err := store.transactDelete(ctx, applied); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := store.GetEvent(ctx, eventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent before migrating = %v, %v, want the event", event, err)
+	}
+	for _, question := range event.Questions {
+		if len(question.Options) != 0 {
+			t.Fatalf("question %s has options before migrating, so the test undid nothing", question.ID)
+		}
+	}
+
+	if err := store.migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	event, err = store.GetEvent(ctx, eventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent after migrating = %v, %v, want the event", event, err)
+	}
+	if len(event.Questions) != 3 {
+		t.Fatalf("event has %d questions after migrating, want 3", len(event.Questions))
+	}
+	for _, question := range event.Questions {
+		if len(question.Options) != 4 || len(question.Ballots) != 4 {
+			t.Errorf("question %s has %d options and %d ballots after migrating, want 4 and 4", question.ID, len(question.Options), len(question.Ballots))
+		}
+		for _, option := range question.Options {
+			if option.Votes != 1 {
+				t.Errorf("option %s has %d votes after migrating, want 1", option.ID, option.Votes)
+			}
+		}
+	}
+
+	result, err := store.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.table),
+		Key: itemKey{
+			"pk": &types.AttributeValueMemberS{Value: string(models.EventEntity) + "#" + eventID},
+			"sk": &types.AttributeValueMemberS{Value: string(models.EventEntity) + "#" + eventID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Item["event_id"] == nil {
+		t.Errorf("event item has no event_id after migrating")
+	}
+}
@@ -2,7 +2,12 @@ package databases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/evoteum/planzoco/go/planzoco/models"
 
@@ -15,19 +20,25 @@ import (
 // Event Operations
 
 // CreateEvent creates a new event in DynamoDB
-func CreateEvent(event models.Event) error {
+func (s *DynamoStore) CreateEvent(ctx context.Context, event models.Event) error {
 	// Make sure the event uses the correct PK/SK pattern
 	if event.PK == "" || event.SK == "" {
-		event = models.NewEvent(event.ID, event.Name)
+		keyed := models.NewEvent(event.ID, event.Name)
+		keyed.AdminToken = event.AdminToken
+		keyed.ModerateOptions = event.ModerateOptions
+		keyed.ClosesAt = event.ClosesAt
+		event = keyed
 	}
 
 	item, err := attributevalue.MarshalMap(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	// The event ID puts the event in its own item collection, see GetEvent
+	item["event_id"] = &types.AttributeValueMemberS{Value: event.ID}
 
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
 		Item:      item,
 	})
 	if err != nil {
@@ -37,143 +48,300 @@ func CreateEvent(event models.Event) error {
 	return nil
 }
 
-// GetEvent retrieves an event by ID from DynamoDB
-func GetEvent(eventID string) (*models.Event, error) {
-	// Create the PK/SK for querying
-	pk := string(models.EventEntity) + "#" + eventID
-	sk := string(models.EventEntity) + "#" + eventID
-
-	result, err := DynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(GetTableName()),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: pk},
-			"sk": &types.AttributeValueMemberS{Value: sk},
+// GetEvent retrieves an event by ID from DynamoDB, with its questions. The
+// event item carries its own event_id, so it comes back from the same query
+// of the EventIDIndex as everything beneath it. The index is only eventually
+// consistent, so an event created a moment ago can be missing from it, in
+// which case the event item is read from the table as well.
+func (s *DynamoStore) GetEvent(ctx context.Context, eventID string) (*models.Event, error) {
+	items, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EventIDIndex"),
+		KeyConditionExpression: aws.String("event_id = :eventID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":eventID": &types.AttributeValueMemberS{Value: eventID},
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get event from DynamoDB: %w", err)
+		return nil, fmt.Errorf("failed to query event from DynamoDB: %w", err)
 	}
 
-	if result.Item == nil {
+	var eventItem map[string]types.AttributeValue
+	for _, item := range items {
+		if entityType(item) == models.EventEntity {
+			eventItem = item
+			break
+		}
+	}
+	if eventItem == nil {
+		key := string(models.EventEntity) + "#" + eventID
+		result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(s.table),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: key},
+				"sk": &types.AttributeValueMemberS{Value: key},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get event from DynamoDB: %w", err)
+		}
+		eventItem = result.Item
+	}
+
+	// An event being deleted is already gone as far as readers are concerned
+	if eventItem == nil || eventItem["deleting"] != nil {
 		return nil, nil
 	}
 
 	var event models.Event
-	err = attributevalue.UnmarshalMap(result.Item, &event)
+	err = attributevalue.UnmarshalMap(eventItem, &event)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal DynamoDB result: %w", err)
 	}
 
-	// Get questions for this event
-	questions, err := GetQuestionsByEventID(eventID)
+	event.Questions, err = unmarshalQuestions(items)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get questions for event: %w", err)
 	}
 
-	event.Questions = questions
 	return &event, nil
 }
 
-// UpdateEvent updates an existing event in DynamoDB
-func UpdateEvent(event models.Event) error {
-	// Ensure the event uses the correct PK/SK pattern
-	if event.PK == "" || event.SK == "" {
-		event = models.NewEvent(event.ID, event.Name)
+// UpdateEvent updates the name, moderation and close time of an existing
+// event in DynamoDB, unless it has changed since event was read. The admin
+// token is never touched, so it cannot be lost or replaced through an edit.
+func (s *DynamoStore) UpdateEvent(ctx context.Context, event models.Event) error {
+	key := string(models.EventEntity) + "#" + event.ID
+
+	condition, values := versionCondition(event.Version)
+	values[":name"] = &types.AttributeValueMemberS{Value: event.Name}
+	values[":moderateOptions"] = &types.AttributeValueMemberBOOL{Value: event.ModerateOptions}
+	update := "SET #name = :name, moderate_options = :moderateOptions, version = :nextVersion REMOVE closes_at"
+	if event.ClosesAt != nil {
+		closesAt, err := attributevalue.Marshal(event.ClosesAt)
+		if err != nil {
+			return fmt.Errorf("failed to marshal close time: %w", err)
+		}
+		update = "SET #name = :name, moderate_options = :moderateOptions, closes_at = :closesAt, version = :nextVersion"
+		values[":closesAt"] = closesAt
 	}
 
-	item, err := attributevalue.MarshalMap(event)
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+			"sk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#name": "name",
+		},
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if conditionFailed.Item != nil {
+			return ErrConflict
+		}
+		return fmt.Errorf("event not found for update: %s", event.ID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+		return fmt.Errorf("failed to update event in DynamoDB: %w", err)
 	}
 
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
-		Item:      item,
+	return nil
+}
+
+// SetAdminToken gives an event without an admin token the token, reporting
+// false if it already had one. Events created before admin tokens existed
+// have no admin_token attribute at all.
+func (s *DynamoStore) SetAdminToken(ctx context.Context, eventID string, token string) (bool, error) {
+	key := string(models.EventEntity) + "#" + eventID
+
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+			"sk": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression:    aws.String("SET admin_token = :token"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND (attribute_not_exists(admin_token) OR admin_token = :none)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":token": &types.AttributeValueMemberS{Value: token},
+			":none":  &types.AttributeValueMemberS{Value: ""},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if conditionFailed.Item != nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("event not found for admin token: %s", eventID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to update event in DynamoDB: %w", err)
+		return false, fmt.Errorf("failed to set admin token in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteEvent deletes an event and all its questions, options, ballots and
+// vetoes. The event is marked for deletion first, which hides it, and then its
+// questions before their options are listed, see deleteCascade. A small event
+// then goes in a single transaction, and a bigger one in batches; if that is
+// cut short, deleting it again or ResumeDeletes finishes the job.
+func (s *DynamoStore) DeleteEvent(ctx context.Context, eventID string) error {
+	if err := s.deleteEvent(ctx, eventID); err != nil {
+		return fmt.Errorf("failed to delete event %s: %w", eventID, err)
 	}
 
 	return nil
 }
 
-// DeleteEvent deletes an event and all associated questions and options
-func DeleteEvent(eventID string) error {
-	// First get questions to get their IDs for deletion
-	questions, err := GetQuestionsByEventID(eventID)
+func (s *DynamoStore) deleteEvent(ctx context.Context, eventID string) error {
+	parents := []itemKey{eventKey(eventID)}
+	marked, err := s.markParents(ctx, parents)
+	if err != nil {
+		return err
+	}
+
+	questions, err := s.eventQuestionKeys(ctx, eventID)
+	if err != nil {
+		return s.abandonDelete(ctx, marked, err)
+	}
+	markedQuestions, err := s.markParents(ctx, questions)
+	marked = append(marked, markedQuestions...)
 	if err != nil {
-		return fmt.Errorf("failed to get questions to delete: %w", err)
+		return s.abandonDelete(ctx, marked, err)
 	}
 
-	// Delete each question and its options
+	var children []itemKey
 	for _, question := range questions {
-		if err := DeleteQuestion(question.ID); err != nil {
-			return fmt.Errorf("failed to delete question %s: %w", question.ID, err)
+		items, err := s.questionItemKeys(ctx, keyID(question, models.QuestionEntity))
+		if err != nil {
+			return s.abandonDelete(ctx, marked, err)
 		}
+		children = append(children, items...)
 	}
+	parents = append(parents, questions...)
 
-	// Delete the event
-	pk := string(models.EventEntity) + "#" + eventID
-	sk := string(models.EventEntity) + "#" + eventID
+	return s.deleteCascade(ctx, parents, children, marked)
+}
 
-	_, err = DynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(GetTableName()),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: pk},
-			"sk": &types.AttributeValueMemberS{Value: sk},
+// ListEvents retrieves all events from DynamoDB using the GSI for entity
+// type, without their questions
+func (s *DynamoStore) ListEvents(ctx context.Context) ([]models.Event, error) {
+	items, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityTypeIndex"),
+		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		FilterExpression:       aws.String("attribute_not_exists(deleting)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityType": &types.AttributeValueMemberS{Value: string(models.EventEntity)},
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to delete event from DynamoDB: %w", err)
+		return nil, fmt.Errorf("failed to query events from DynamoDB: %w", err)
 	}
 
-	return nil
+	var events []models.Event
+	err = attributevalue.UnmarshalListOfMaps(items, &events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+	}
+
+	return events, nil
 }
 
-// ListEvents retrieves all events from DynamoDB using the GSI for entity type
-func ListEvents() ([]models.Event, error) {
-	// Use the EntityTypeIndex to query for events
-	queryInput := &dynamodb.QueryInput{
-		TableName:              aws.String(GetTableName()),
+// ListEventsPage retrieves a page of events, without their questions, in one
+// query of the EntityTypeIndex. The cursor is the query's LastEvaluatedKey.
+// Events being deleted are filtered out after the page is read, so a page can
+// come back short, even empty, with more to come.
+func (s *DynamoStore) ListEventsPage(ctx context.Context, limit int, cursor string) ([]models.Event, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
 		IndexName:              aws.String("EntityTypeIndex"),
 		KeyConditionExpression: aws.String("entity_type = :entityType"),
+		FilterExpression:       aws.String("attribute_not_exists(deleting)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":entityType": &types.AttributeValueMemberS{Value: string(models.EventEntity)},
 		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		var after map[string]string
+		if err := decodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		start, err := eventPageStart(after)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = start
 	}
 
-	result, err := DynamoClient.Query(context.TODO(), queryInput)
+	result, err := s.client.Query(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query events from DynamoDB: %w", err)
+		return nil, "", fmt.Errorf("failed to query events from DynamoDB: %w", err)
 	}
 
 	var events []models.Event
 	err = attributevalue.UnmarshalListOfMaps(result.Items, &events)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		return nil, "", fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
 	}
 
-	// Get questions for each event
-	for i := range events {
-		questions, err := GetQuestionsByEventID(events[i].ID)
-		if err != nil {
-			// Continue with empty questions if we can't get them
-			events[i].Questions = []models.Question{}
-			continue
+	var next string
+	if len(result.LastEvaluatedKey) > 0 {
+		after := make(map[string]string, len(result.LastEvaluatedKey))
+		for name, value := range result.LastEvaluatedKey {
+			if value, ok := value.(*types.AttributeValueMemberS); ok {
+				after[name] = value.Value
+			}
 		}
-		events[i].Questions = questions
+		next = encodeCursor(after)
 	}
 
-	return events, nil
+	return events, next, nil
+}
+
+// eventPageStart turns a decoded ListEventsPage cursor back into the key the
+// query starts after, returning ErrInvalidCursor unless it is the key of an
+// event on the EntityTypeIndex, which DynamoDB would otherwise reject
+func eventPageStart(after map[string]string) (map[string]types.AttributeValue, error) {
+	if len(after) != 3 || after["entity_type"] != string(models.EventEntity) || after["pk"] == "" || after["sk"] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	start := make(map[string]types.AttributeValue, len(after))
+	for name, value := range after {
+		start[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return start, nil
 }
 
 // Question Operations
 
 // AddQuestion creates a new question in DynamoDB
-func AddQuestion(eventID string, question models.Question) error {
+func (s *DynamoStore) AddQuestion(ctx context.Context, eventID string, question models.Question) error {
 	// Make sure the question uses the correct PK/SK pattern
 	if question.PK == "" || question.SK == "" {
-		question = models.NewQuestion(question.ID, eventID, question.Text)
+		keyed := models.NewQuestion(question.ID, eventID, question.Text)
+		keyed.Type = question.Type
+		keyed.MaxSelections = question.MaxSelections
+		keyed.ScaleMin, keyed.ScaleMax = question.ScaleMin, question.ScaleMax
+		keyed.WinBy = question.WinBy
+		keyed.ClosesAt = question.ClosesAt
+		keyed.TieBreak = question.TieBreak
+		keyed.MinParticipants = question.MinParticipants
+		keyed.Majority, keyed.MajorityPercent = question.Majority, question.MajorityPercent
+		keyed.VetoLimit = question.VetoLimit
+		keyed.HideResults, keyed.ResultsRevealed = question.HideResults, question.ResultsRevealed
+		keyed.Locked = question.Locked
+		question = keyed
 	}
 
 	item, err := attributevalue.MarshalMap(question)
@@ -181,25 +349,27 @@ func AddQuestion(eventID string, question models.Question) error {
 		return fmt.Errorf("failed to marshal question: %w", err)
 	}
 
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
-		Item:      item,
-	})
+	// A question added to an event being deleted would outlive it
+	added, err := s.putChild(ctx, eventKey(eventID), item)
 	if err != nil {
 		return fmt.Errorf("failed to put question in DynamoDB: %w", err)
 	}
+	if !added {
+		return fmt.Errorf("event not found: %s", eventID)
+	}
 
 	return nil
 }
 
 // GetQuestion retrieves a question by ID from DynamoDB
-func GetQuestion(questionID string) (*models.Question, error) {
+func (s *DynamoStore) GetQuestion(ctx context.Context, questionID string) (*models.Question, error) {
 	// First, we need to find which event this question belongs to by querying the GSI
 	// We can't directly get it because we don't know the SK (event ID)
-	result, err := DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              aws.String(GetTableName()),
+	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
 		IndexName:              aws.String("EntityTypeIndex"),
 		KeyConditionExpression: aws.String("entity_type = :entityType AND pk = :pk"),
+		FilterExpression:       aws.String("attribute_not_exists(deleting)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":entityType": &types.AttributeValueMemberS{Value: string(models.QuestionEntity)},
 			":pk":         &types.AttributeValueMemberS{Value: string(models.QuestionEntity) + "#" + questionID},
@@ -219,19 +389,21 @@ func GetQuestion(questionID string) (*models.Question, error) {
 		return nil, fmt.Errorf("failed to unmarshal DynamoDB result: %w", err)
 	}
 
-	// Get options for this question
-	options, err := GetOptionsByQuestionID(questionID)
+	// Get options, ballots and vetoes for this question
+	options, ballots, vetoes, err := s.queryQuestionItems(ctx, questionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get options for question: %w", err)
 	}
 
 	question.Options = options
+	question.Ballots = ballots
+	question.Vetoes = vetoes
 	return &question, nil
 }
 
 // GetQuestionWithEvent retrieves a question with its associated event
-func GetQuestionWithEvent(questionID string) (*models.Question, *models.Event, error) {
-	question, err := GetQuestion(questionID)
+func (s *DynamoStore) GetQuestionWithEvent(ctx context.Context, questionID string) (*models.Question, *models.Event, error) {
+	question, err := s.GetQuestion(ctx, questionID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -240,7 +412,7 @@ func GetQuestionWithEvent(questionID string) (*models.Question, *models.Event, e
 		return nil, nil, nil
 	}
 
-	event, err := GetEvent(question.EventID)
+	event, err := s.GetEvent(ctx, question.EventID)
 	if err != nil {
 		return question, nil, err
 	}
@@ -248,32 +420,62 @@ func GetQuestionWithEvent(questionID string) (*models.Question, *models.Event, e
 	return question, event, nil
 }
 
-// UpdateQuestion updates an existing question in DynamoDB
-func UpdateQuestion(question models.Question) error {
-	// Ensure the question uses the correct PK/SK pattern
-	if question.PK == "" || question.SK == "" {
-		existingQuestion, err := GetQuestion(question.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get existing question for update: %w", err)
-		}
-		if existingQuestion == nil {
-			return fmt.Errorf("question not found for update: %s", question.ID)
-		}
-
-		question = models.NewQuestion(question.ID, existingQuestion.EventID, question.Text)
-		// Preserve options
-		question.Options = existingQuestion.Options
+// UpdateQuestion updates an existing question in DynamoDB, unless it has
+// changed since question was read. The whole item is replaced, so a question
+// marked for deletion is left alone rather than brought back without its mark,
+// and it is rebuilt from the stored one, so the fields an update cannot
+// change stay as they are even if the caller's copy has them changed.
+func (s *DynamoStore) UpdateQuestion(ctx context.Context, question models.Question) error {
+	existingQuestion, err := s.GetQuestion(ctx, question.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get existing question for update: %w", err)
+	}
+	if existingQuestion == nil {
+		return fmt.Errorf("question not found for update: %s", question.ID)
 	}
 
+	keyed := models.NewQuestion(question.ID, existingQuestion.EventID, question.Text)
+	// The event, type and scale are fixed once the question exists
+	keyed.Type = existingQuestion.Type
+	keyed.MaxSelections = question.MaxSelections
+	keyed.ScaleMin, keyed.ScaleMax = existingQuestion.ScaleMin, existingQuestion.ScaleMax
+	keyed.WinBy = question.WinBy
+	keyed.ClosesAt = question.ClosesAt
+	keyed.TieBreak = question.TieBreak
+	keyed.MinParticipants = question.MinParticipants
+	keyed.Majority, keyed.MajorityPercent = question.Majority, question.MajorityPercent
+	keyed.VetoLimit = question.VetoLimit
+	keyed.HideResults, keyed.ResultsRevealed = question.HideResults, question.ResultsRevealed
+	keyed.Locked = question.Locked
+	// The decision is only set by CloseQuestion
+	keyed.Decision = existingQuestion.Decision
+	keyed.Version = question.Version
+	question = keyed
+
+	condition, values := versionCondition(question.Version)
+	condition = "attribute_not_exists(deleting) AND (" + condition + ")"
+	question.Version++
 	item, err := attributevalue.MarshalMap(question)
 	if err != nil {
 		return fmt.Errorf("failed to marshal question: %w", err)
 	}
-
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
-		Item:      item,
+	// The item itself carries the next version, a put has no update to set it
+	delete(values, ":nextVersion")
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           aws.String(s.table),
+		Item:                                item,
+		ConditionExpression:                 aws.String(condition),
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if conditionFailed.Item != nil && conditionFailed.Item["deleting"] == nil {
+			return ErrConflict
+		}
+		return fmt.Errorf("question not found for update: %s", question.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update question in DynamoDB: %w", err)
 	}
@@ -281,120 +483,242 @@ func UpdateQuestion(question models.Question) error {
 	return nil
 }
 
-// DeleteQuestion deletes a question and all its options
-func DeleteQuestion(questionID string) error {
-	// First get the question to find its event ID and options
-	question, err := GetQuestion(questionID)
+// CloseQuestion stores the decision of a question unless it already has one
+func (s *DynamoStore) CloseQuestion(ctx context.Context, questionID string, decision models.Decision) (bool, error) {
+	// The sort key holds the event ID, which only the stored question knows
+	question, err := s.GetQuestion(ctx, questionID)
 	if err != nil {
-		return fmt.Errorf("failed to get question to delete: %w", err)
+		return false, fmt.Errorf("failed to get question to close: %w", err)
 	}
-
 	if question == nil {
-		return fmt.Errorf("question not found for deletion: %s", questionID)
+		return false, fmt.Errorf("question not found for closing: %s", questionID)
 	}
 
-	// Delete all options for this question
-	options, err := GetOptionsByQuestionID(questionID)
+	value, err := attributevalue.Marshal(decision)
 	if err != nil {
-		return fmt.Errorf("failed to get options to delete: %w", err)
+		return false, fmt.Errorf("failed to marshal decision: %w", err)
 	}
 
-	for _, option := range options {
-		if err := DeleteOption(option.ID); err != nil {
-			return fmt.Errorf("failed to delete option %s: %w", option.ID, err)
-		}
+	_, err = s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: question.PK},
+			"sk": &types.AttributeValueMemberS{Value: question.SK},
+		},
+		UpdateExpression:    aws.String("SET decision = :decision ADD version :one"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_not_exists(decision)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":decision": value,
+			":one":      &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to close question in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// UpdateDecision replaces the decision of a decided question
+func (s *DynamoStore) UpdateDecision(ctx context.Context, questionID string, decision models.Decision) error {
+	question, err := s.GetQuestion(ctx, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to get question to update its decision: %w", err)
+	}
+	if question == nil {
+		return fmt.Errorf("decided question not found: %s", questionID)
 	}
 
-	// Delete the question using PK/SK
-	pk := string(models.QuestionEntity) + "#" + questionID
-	sk := string(models.EventEntity) + "#" + question.EventID
+	value, err := attributevalue.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decision: %w", err)
+	}
 
-	_, err = DynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(GetTableName()),
+	_, err = s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.table),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: pk},
-			"sk": &types.AttributeValueMemberS{Value: sk},
+			"pk": &types.AttributeValueMemberS{Value: question.PK},
+			"sk": &types.AttributeValueMemberS{Value: question.SK},
+		},
+		UpdateExpression:    aws.String("SET decision = :decision ADD version :one"),
+		ConditionExpression: aws.String("attribute_exists(decision)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":decision": value,
+			":one":      &types.AttributeValueMemberN{Value: "1"},
 		},
 	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return fmt.Errorf("decided question not found: %s", questionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update decision in DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteQuestion deletes a question and all its options, ballots and vetoes,
+// the way DeleteEvent does
+func (s *DynamoStore) DeleteQuestion(ctx context.Context, questionID string) error {
+	key, err := s.questionKey(ctx, questionID)
 	if err != nil {
-		return fmt.Errorf("failed to delete question from DynamoDB: %w", err)
+		return fmt.Errorf("failed to get question to delete: %w", err)
+	}
+
+	if key == nil {
+		return fmt.Errorf("question not found for deletion: %s", questionID)
+	}
+
+	if err := s.deleteQuestion(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete question %s: %w", questionID, err)
 	}
 
 	return nil
 }
 
-// GetQuestionsByEventID retrieves all questions for a given event ID
-func GetQuestionsByEventID(eventID string) ([]models.Question, error) {
-	// Query using the EventIDIndex
-	queryInput := &dynamodb.QueryInput{
-		TableName:              aws.String(GetTableName()),
+// GetQuestionsByEventID retrieves all questions for a given event ID, with
+// their options, ballots and vetoes. Every item of an event carries its
+// event_id, so they all come back from a single query of the EventIDIndex.
+func (s *DynamoStore) GetQuestionsByEventID(ctx context.Context, eventID string) ([]models.Question, error) {
+	items, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
 		IndexName:              aws.String("EventIDIndex"),
 		KeyConditionExpression: aws.String("event_id = :eventID"),
-		FilterExpression:       aws.String("entity_type = :entityType"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":eventID":    &types.AttributeValueMemberS{Value: eventID},
-			":entityType": &types.AttributeValueMemberS{Value: string(models.QuestionEntity)},
+			":eventID": &types.AttributeValueMemberS{Value: eventID},
 		},
-	}
-
-	result, err := DynamoClient.Query(context.TODO(), queryInput)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query questions by event ID: %w", err)
 	}
 
-	var questions []models.Question
-	if len(result.Items) == 0 {
-		return questions, nil
-	}
+	return unmarshalQuestions(items)
+}
 
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &questions)
+// ListDueQuestionIDs retrieves the IDs of the undecided questions whose close
+// time, or their event's, has passed. Only events and questions with a close
+// time are in the sparse ClosesAtIndex, so a scan of it finds the questions
+// with close times of their own and the events with one. The undecided
+// questions of each event whose close time has passed are then queried from
+// its item collection.
+func (s *DynamoStore) ListDueQuestionIDs(ctx context.Context, now time.Time) ([]string, error) {
+	items, err := s.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(s.table),
+		IndexName:            aws.String("ClosesAtIndex"),
+		FilterExpression:     aws.String("attribute_not_exists(decision) AND attribute_not_exists(deleting)"),
+		ProjectionExpression: aws.String("entity_type, id, closes_at"),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		return nil, fmt.Errorf("failed to scan close times from DynamoDB: %w", err)
 	}
 
-	// Get options for each question
-	for i := range questions {
-		options, err := GetOptionsByQuestionID(questions[i].ID)
-		if err != nil {
-			continue
+	var questionIDs []string
+	due := make(map[string]bool)
+	for _, item := range items {
+		switch entityType(item) {
+		case models.QuestionEntity:
+			var question models.Question
+			if err := attributevalue.UnmarshalMap(item, &question); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal DynamoDB scan result: %w", err)
+			}
+			if question.DueToClose(nil, now) && !due[question.ID] {
+				due[question.ID] = true
+				questionIDs = append(questionIDs, question.ID)
+			}
+		case models.EventEntity:
+			var event models.Event
+			if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal DynamoDB scan result: %w", err)
+			}
+			if event.ClosesAt == nil || now.Before(*event.ClosesAt) {
+				continue
+			}
+
+			questions, err := s.queryAll(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(s.table),
+				IndexName:              aws.String("EventIDIndex"),
+				KeyConditionExpression: aws.String("event_id = :eventID AND begins_with(pk, :question)"),
+				FilterExpression:       aws.String("attribute_not_exists(decision) AND attribute_not_exists(deleting)"),
+				ProjectionExpression:   aws.String("id"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":eventID":  &types.AttributeValueMemberS{Value: event.ID},
+					":question": &types.AttributeValueMemberS{Value: string(models.QuestionEntity) + "#"},
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to query questions of event %s from DynamoDB: %w", event.ID, err)
+			}
+			for _, item := range questions {
+				id, _ := item["id"].(*types.AttributeValueMemberS)
+				if id != nil && !due[id.Value] {
+					due[id.Value] = true
+					questionIDs = append(questionIDs, id.Value)
+				}
+			}
 		}
-		questions[i].Options = options
 	}
 
-	return questions, nil
+	return questionIDs, nil
 }
 
 // Option Operations
 
 // AddOption creates a new option in DynamoDB
-func AddOption(questionID string, option models.Option) error {
+func (s *DynamoStore) AddOption(ctx context.Context, questionID string, option models.Option) error {
 	// Make sure the option uses the correct PK/SK pattern
 	if option.PK == "" || option.SK == "" {
-		option = models.NewOption(option.ID, questionID, option.Text)
+		keyed := models.NewOption(option.ID, questionID, option.Text)
+		keyed.Slot = option.Slot
+		keyed.Pending = option.Pending
+		keyed.CreatedAt = option.CreatedAt
+		option = keyed
 	}
+	option.CreatedAt = createdAt(option.CreatedAt)
 
 	item, err := attributevalue.MarshalMap(option)
 	if err != nil {
 		return fmt.Errorf("failed to marshal option: %w", err)
 	}
 
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
-		Item:      item,
-	})
+	question, err := s.questionKey(ctx, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to find question of option: %w", err)
+	}
+	if question == nil {
+		return fmt.Errorf("question not found: %s", questionID)
+	}
+
+	// The event ID puts the option in its event's item collection, see
+	// GetQuestionsByEventID
+	eventID, err := questionKeyEventID(question)
+	if err != nil {
+		return err
+	}
+	item["event_id"] = &types.AttributeValueMemberS{Value: eventID}
+
+	// An option added to a question being deleted would outlive it
+	added, err := s.putChild(ctx, question, item)
 	if err != nil {
 		return fmt.Errorf("failed to put option in DynamoDB: %w", err)
 	}
+	if !added {
+		return fmt.Errorf("question not found: %s", questionID)
+	}
 
 	return nil
 }
 
 // GetOption retrieves an option by ID from DynamoDB
-func GetOption(optionID string) (*models.Option, error) {
+func (s *DynamoStore) GetOption(ctx context.Context, optionID string) (*models.Option, error) {
 	// First, we need to find which question this option belongs to by querying the GSI
 	// We can't directly get it because we don't know the SK (question ID)
-	result, err := DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              aws.String(GetTableName()),
+	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
 		IndexName:              aws.String("EntityTypeIndex"),
 		KeyConditionExpression: aws.String("entity_type = :entityType AND pk = :pk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
@@ -416,35 +740,95 @@ func GetOption(optionID string) (*models.Option, error) {
 		return nil, fmt.Errorf("failed to unmarshal DynamoDB result: %w", err)
 	}
 
+	// Count the ballots cast for this option, which availability answers of
+	// "no" are not
+	votes, err := s.countAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID"),
+		FilterExpression:       aws.String("entity_type = :entityType AND option_id = :optionID AND (attribute_not_exists(#availability) OR #availability <> :no)"),
+		ExpressionAttributeNames: map[string]string{
+			"#availability": "availability",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID": &types.AttributeValueMemberS{Value: option.QuestionID},
+			":entityType": &types.AttributeValueMemberS{Value: string(models.BallotEntity)},
+			":optionID":   &types.AttributeValueMemberS{Value: optionID},
+			":no":         &types.AttributeValueMemberS{Value: string(models.AvailableNo)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count votes for option: %w", err)
+	}
+
+	vetoes, err := s.countAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID"),
+		FilterExpression:       aws.String("entity_type = :entityType AND option_id = :optionID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID": &types.AttributeValueMemberS{Value: option.QuestionID},
+			":entityType": &types.AttributeValueMemberS{Value: string(models.VetoEntity)},
+			":optionID":   &types.AttributeValueMemberS{Value: optionID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count vetoes for option: %w", err)
+	}
+
+	option.Votes = votes
+	option.Vetoes = vetoes
 	return &option, nil
 }
 
-// UpdateOption updates an existing option in DynamoDB
-func UpdateOption(option models.Option) error {
-	// Ensure the option uses the correct PK/SK pattern
-	if option.PK == "" || option.SK == "" {
-		existingOption, err := GetOption(option.ID)
+// UpdateOption updates the text, slot and approval of an existing option in
+// DynamoDB, unless it has changed since option was read. Only those
+// attributes are written, so a vote landing at the same moment is kept.
+func (s *DynamoStore) UpdateOption(ctx context.Context, option models.Option) error {
+	// We need the question ID to build the SK
+	questionID := option.QuestionID
+	if questionID == "" {
+		existingOption, err := s.GetOption(ctx, option.ID)
 		if err != nil {
 			return fmt.Errorf("failed to get existing option for update: %w", err)
 		}
 		if existingOption == nil {
 			return fmt.Errorf("option not found for update: %s", option.ID)
 		}
-
-		option = models.NewOption(option.ID, existingOption.QuestionID, option.Text)
-		// Preserve votes
-		option.Votes = existingOption.Votes
+		questionID = existingOption.QuestionID
 	}
 
-	item, err := attributevalue.MarshalMap(option)
-	if err != nil {
-		return fmt.Errorf("failed to marshal option: %w", err)
+	condition, values := versionCondition(option.Version)
+	values[":text"] = &types.AttributeValueMemberS{Value: option.Text}
+	values[":pending"] = &types.AttributeValueMemberBOOL{Value: option.Pending}
+	update := "SET #text = :text, pending = :pending, version = :nextVersion REMOVE slot"
+	if option.Slot != nil {
+		slot, err := attributevalue.Marshal(option.Slot)
+		if err != nil {
+			return fmt.Errorf("failed to marshal slot: %w", err)
+		}
+		update = "SET #text = :text, pending = :pending, slot = :slot, version = :nextVersion"
+		values[":slot"] = slot
 	}
 
-	_, err = DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(GetTableName()),
-		Item:      item,
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 optionKey(option.ID, questionID),
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#text": "text",
+		},
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		if conditionFailed.Item != nil {
+			return ErrConflict
+		}
+		return fmt.Errorf("option not found for update: %s", option.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update option in DynamoDB: %w", err)
 	}
@@ -452,10 +836,11 @@ func UpdateOption(option models.Option) error {
 	return nil
 }
 
-// DeleteOption deletes an option by ID from DynamoDB
-func DeleteOption(optionID string) error {
+// DeleteOption deletes an option by ID from DynamoDB, along with the ballots
+// and vetoes cast on it
+func (s *DynamoStore) DeleteOption(ctx context.Context, optionID string) error {
 	// First get the option to find its question ID
-	option, err := GetOption(optionID)
+	option, err := s.GetOption(ctx, optionID)
 	if err != nil {
 		return fmt.Errorf("failed to get option to delete: %w", err)
 	}
@@ -464,64 +849,569 @@ func DeleteOption(optionID string) error {
 		return fmt.Errorf("option not found for deletion: %s", optionID)
 	}
 
-	// Delete the option using PK/SK
-	pk := string(models.OptionEntity) + "#" + optionID
-	sk := string(models.QuestionEntity) + "#" + option.QuestionID
+	// Marking the option first stops new ballots on it, see checkOptions
+	parents := []itemKey{optionKey(optionID, option.QuestionID)}
+	marked, err := s.markParents(ctx, parents)
+	if err != nil {
+		return fmt.Errorf("failed to delete option %s: %w", optionID, err)
+	}
 
-	_, err = DynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(GetTableName()),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: pk},
-			"sk": &types.AttributeValueMemberS{Value: sk},
+	// Ballots and vetoes for the option go with it
+	ballots, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID"),
+		FilterExpression:       aws.String("entity_type IN (:ballotEntity, :vetoEntity) AND option_id = :optionID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID":   &types.AttributeValueMemberS{Value: option.QuestionID},
+			":ballotEntity": &types.AttributeValueMemberS{Value: string(models.BallotEntity)},
+			":vetoEntity":   &types.AttributeValueMemberS{Value: string(models.VetoEntity)},
+			":optionID":     &types.AttributeValueMemberS{Value: optionID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get ballots to delete: %w", s.abandonDelete(ctx, marked, err))
+	}
+
+	if err := s.deleteCascade(ctx, parents, ballots, marked); err != nil {
+		return fmt.Errorf("failed to delete option %s: %w", optionID, err)
+	}
+
+	return nil
+}
+
+// GetOptionsByQuestionID retrieves all options for a given question ID
+func (s *DynamoStore) GetOptionsByQuestionID(ctx context.Context, questionID string) ([]models.Option, error) {
+	options, _, _, err := s.queryQuestionItems(ctx, questionID)
+	return options, err
+}
+
+// queryQuestionItems retrieves the options of a question along with the
+// ballots and vetoes cast on it in a single query, and counts the votes and
+// vetoes of each option
+func (s *DynamoStore) queryQuestionItems(ctx context.Context, questionID string) ([]models.Option, []models.Ballot, []models.Veto, error) {
+	// Query using the QuestionIDIndex
+	items, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID"),
+		FilterExpression:       aws.String("entity_type IN (:optionEntity, :ballotEntity, :vetoEntity)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID":   &types.AttributeValueMemberS{Value: questionID},
+			":optionEntity": &types.AttributeValueMemberS{Value: string(models.OptionEntity)},
+			":ballotEntity": &types.AttributeValueMemberS{Value: string(models.BallotEntity)},
+			":vetoEntity":   &types.AttributeValueMemberS{Value: string(models.VetoEntity)},
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to delete option from DynamoDB: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to query options by question ID: %w", err)
+	}
+
+	return unmarshalQuestionItems(items)
+}
+
+// unmarshalQuestionItems picks the options, ballots and vetoes out of the
+// given items, puts the options in the order they were created and counts
+// their votes and vetoes. Other items are skipped.
+func unmarshalQuestionItems(items []map[string]types.AttributeValue) ([]models.Option, []models.Ballot, []models.Veto, error) {
+	var options []models.Option
+	var ballots []models.Ballot
+	var vetoes []models.Veto
+	for _, item := range items {
+		switch entityType(item) {
+		case models.OptionEntity:
+			var option models.Option
+			if err := attributevalue.UnmarshalMap(item, &option); err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+			}
+			options = append(options, option)
+		case models.BallotEntity:
+			var ballot models.Ballot
+			if err := attributevalue.UnmarshalMap(item, &ballot); err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+			}
+			ballots = append(ballots, ballot)
+		case models.VetoEntity:
+			var veto models.Veto
+			if err := attributevalue.UnmarshalMap(item, &veto); err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+			}
+			vetoes = append(vetoes, veto)
+		}
+	}
+
+	// The index keeps no order, so put the options back in the order they were
+	// created. Options from before creation times were kept sort first.
+	sort.SliceStable(options, func(i, j int) bool {
+		if !options[i].CreatedAt.Equal(options[j].CreatedAt) {
+			return options[i].CreatedAt.Before(options[j].CreatedAt)
+		}
+		return options[i].ID < options[j].ID
+	})
+
+	models.CountVotes(options, ballots)
+	models.CountVetoes(options, vetoes)
+	return options, ballots, vetoes, nil
+}
+
+// unmarshalQuestions picks the questions out of the given items and attaches
+// the options, ballots and vetoes among the items to them. Questions being
+// deleted are left out.
+func unmarshalQuestions(items []map[string]types.AttributeValue) ([]models.Question, error) {
+	var questions []models.Question
+	for _, item := range items {
+		if entityType(item) != models.QuestionEntity || item["deleting"] != nil {
+			continue
+		}
+		var question models.Question
+		if err := attributevalue.UnmarshalMap(item, &question); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		}
+		questions = append(questions, question)
+	}
+
+	options, ballots, vetoes, err := unmarshalQuestionItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsByQuestion := make(map[string][]models.Option)
+	for _, option := range options {
+		optionsByQuestion[option.QuestionID] = append(optionsByQuestion[option.QuestionID], option)
+	}
+	ballotsByQuestion := make(map[string][]models.Ballot)
+	for _, ballot := range ballots {
+		ballotsByQuestion[ballot.QuestionID] = append(ballotsByQuestion[ballot.QuestionID], ballot)
+	}
+	vetoesByQuestion := make(map[string][]models.Veto)
+	for _, veto := range vetoes {
+		vetoesByQuestion[veto.QuestionID] = append(vetoesByQuestion[veto.QuestionID], veto)
+	}
+	for i := range questions {
+		questions[i].Options = optionsByQuestion[questions[i].ID]
+		questions[i].Ballots = ballotsByQuestion[questions[i].ID]
+		questions[i].Vetoes = vetoesByQuestion[questions[i].ID]
+	}
+
+	return questions, nil
+}
+
+// queryAll runs a query through every page of results
+func (s *DynamoStore) queryAll(ctx context.Context, input *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for {
+		result, err := s.client.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// scanAll runs a scan through every page of results
+func (s *DynamoStore) scanAll(ctx context.Context, input *dynamodb.ScanInput) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for {
+		result, err := s.client.Scan(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// countAll runs a counting query through every page of results and adds up
+// the counts
+func (s *DynamoStore) countAll(ctx context.Context, input *dynamodb.QueryInput) (int, error) {
+	input.Select = types.SelectCount
+
+	count := 0
+	for {
+		result, err := s.client.Query(ctx, input)
+		if err != nil {
+			return 0, err
+		}
+		count += int(result.Count)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// entityType returns the entity type of an item
+func entityType(item map[string]types.AttributeValue) models.EntityType {
+	value, _ := item["entity_type"].(*types.AttributeValueMemberS)
+	if value == nil {
+		return ""
+	}
+	return models.EntityType(value.Value)
+}
+
+// questionKeyEventID returns the ID of the event a question belongs to, which
+// its sort key names
+func questionKeyEventID(key itemKey) (string, error) {
+	sk, _ := key["sk"].(*types.AttributeValueMemberS)
+	if sk == nil {
+		return "", fmt.Errorf("question %s has no event", keyID(key, models.QuestionEntity))
+	}
+	return strings.TrimPrefix(sk.Value, string(models.EventEntity)+"#"), nil
+}
+
+// Participant Operations
+
+// SaveParticipant creates or replaces a participant in DynamoDB
+func (s *DynamoStore) SaveParticipant(ctx context.Context, participant models.Participant) error {
+	// Make sure the participant uses the correct PK/SK pattern
+	if participant.PK == "" || participant.SK == "" {
+		participant = models.NewParticipant(participant.ID, participant.Name)
+	}
+
+	item, err := attributevalue.MarshalMap(participant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participant: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put participant in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// VoteOption increments the vote count for an option
-func VoteOption(optionID string) error {
-	// First, get the current option
-	option, err := GetOption(optionID)
+// GetParticipant retrieves a participant by ID from DynamoDB
+func (s *DynamoStore) GetParticipant(ctx context.Context, participantID string) (*models.Participant, error) {
+	key := string(models.ParticipantEntity) + "#" + participantID
+
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+			"sk": &types.AttributeValueMemberS{Value: key},
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get option to vote: %w", err)
+		return nil, fmt.Errorf("failed to get participant from DynamoDB: %w", err)
 	}
-	if option == nil {
-		return fmt.Errorf("option not found: %s", optionID)
+
+	if result.Item == nil {
+		return nil, nil
 	}
 
-	// Increment vote count
-	option.Votes++
+	var participant models.Participant
+	err = attributevalue.UnmarshalMap(result.Item, &participant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DynamoDB result: %w", err)
+	}
 
-	// Save back to DynamoDB
-	return UpdateOption(*option)
+	return &participant, nil
 }
 
-// GetOptionsByQuestionID retrieves all options for a given question ID
-func GetOptionsByQuestionID(questionID string) ([]models.Option, error) {
-	// Query using the QuestionIDIndex
-	result, err := DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              aws.String(GetTableName()),
-		IndexName:              aws.String("QuestionIDIndex"),
-		KeyConditionExpression: aws.String("question_id = :questionID"),
-		FilterExpression:       aws.String("entity_type = :entityType"),
+// Ballot Operations
+
+// GetBallotsByQuestionID retrieves every ballot cast on a question
+func (s *DynamoStore) GetBallotsByQuestionID(ctx context.Context, questionID string) ([]models.Ballot, error) {
+	_, ballots, _, err := s.queryQuestionItems(ctx, questionID)
+	return ballots, err
+}
+
+// ReplaceBallots swaps all of a participant's ballots on a question for the
+// given ones in a single transaction.
+//
+// Next to the ballots, each participant has a ballot box item per question
+// holding a revision number. Every transaction bumps the revision on the
+// condition that it has not changed since the ballots were read, so two
+// submissions racing each other cannot both succeed; the loser retries, and
+// returns ErrConflict once it has lost maxBallotAttempts times.
+func (s *DynamoStore) ReplaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error {
+	if err := checkBallots(questionID, participantID, ballots); err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < maxBallotAttempts; attempt++ {
+		err := s.replaceBallots(ctx, questionID, participantID, ballots)
+		if !errors.Is(err, errBallotConflict) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to replace ballots after %d attempts: %w", maxBallotAttempts, ErrConflict)
+}
+
+func (s *DynamoStore) replaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error {
+	var items []partitionItem
+	for _, ballot := range ballots {
+		rank, availability, score := ballot.Rank, ballot.Availability, ballot.Score
+		ballot = models.NewBallot(questionID, models.NewParticipant(participantID, ballot.ParticipantName), ballot.OptionID)
+		ballot.Rank, ballot.Availability, ballot.Score = rank, availability, score
+
+		item, err := attributevalue.MarshalMap(ballot)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ballot: %w", err)
+		}
+		items = append(items, partitionItem{SK: ballot.SK, OptionID: ballot.OptionID, Item: item})
+	}
+
+	return s.replacePartition(ctx, models.BallotPartition(questionID, participantID), questionID, items)
+}
+
+// partitionItem is a ballot or veto to put in a participant's partition
+type partitionItem struct {
+	SK       string
+	OptionID string
+	Item     map[string]types.AttributeValue
+}
+
+// replacePartition swaps the items in a participant's ballot or veto
+// partition for the given ones, bumping the partition's ballot box, see
+// ReplaceBallots. The options of the items are checked against the
+// question's options before writing, rather than with a condition check per
+// item, which would leave room in a transaction for fewer than half the slots
+// an availability question can have. The transaction only checks that the
+// question itself still exists.
+func (s *DynamoStore) replacePartition(ctx context.Context, partition string, questionID string, wanted []partitionItem) error {
+	// Read the ballot box and the current items, which share a partition
+	partitionItems, err := s.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("pk = :pk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":questionID": &types.AttributeValueMemberS{Value: questionID},
-			":entityType": &types.AttributeValueMemberS{Value: string(models.OptionEntity)},
+			":pk": &types.AttributeValueMemberS{Value: partition},
 		},
+		ConsistentRead: aws.Bool(true),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query options by question ID: %w", err)
+		return fmt.Errorf("failed to query partition %s from DynamoDB: %w", partition, err)
 	}
 
-	var options []models.Option
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &options)
+	var box *ballotBox
+	existing := make(map[string]bool)
+	for _, item := range partitionItems {
+		var key models.DynamoItem
+		if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+			return fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		}
+		if key.SK != ballotBoxSK {
+			existing[key.SK] = true
+			continue
+		}
+
+		box = &ballotBox{}
+		if err := attributevalue.UnmarshalMap(item, box); err != nil {
+			return fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		}
+	}
+
+	question, err := s.questionKey(ctx, questionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal DynamoDB query result: %w", err)
+		return fmt.Errorf("failed to find question of partition %s: %w", partition, err)
+	}
+	if question == nil {
+		return fmt.Errorf("question not found: %s", questionID)
+	}
+	if err := s.checkOptions(ctx, questionID, wanted); err != nil {
+		return err
+	}
+
+	// Everything in the partition joins its event's item collection, see
+	// GetQuestionsByEventID
+	eventID, err := questionKeyEventID(question)
+	if err != nil {
+		return err
+	}
+
+	var items []types.TransactWriteItem
+
+	// Bump the ballot box revision, failing if someone else got there first
+	next := ballotBox{
+		DynamoItem: models.DynamoItem{PK: partition, SK: ballotBoxSK},
+		QuestionID: questionID,
+		EventID:    eventID,
+		EntityType: ballotBoxEntity,
+	}
+	condition := "attribute_not_exists(pk)"
+	values := map[string]types.AttributeValue{}
+	if box != nil {
+		next.Revision = box.Revision + 1
+		condition = "revision = :revision"
+		values[":revision"] = &types.AttributeValueMemberN{Value: strconv.Itoa(box.Revision)}
+	}
+	boxItem, err := attributevalue.MarshalMap(next)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ballot box: %w", err)
+	}
+	put := &types.Put{
+		TableName:           aws.String(s.table),
+		Item:                boxItem,
+		ConditionExpression: aws.String(condition),
+	}
+	if len(values) > 0 {
+		put.ExpressionAttributeValues = values
+	}
+	items = append(items, types.TransactWriteItem{Put: put})
+
+	// Only write if the question is still there and not being deleted
+	items = append(items, s.parentCheck(question))
+
+	// Put the new items
+	kept := make(map[string]bool)
+	for _, item := range wanted {
+		kept[item.SK] = true
+		item.Item["event_id"] = &types.AttributeValueMemberS{Value: eventID}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{
+			TableName: aws.String(s.table),
+			Item:      item.Item,
+		}})
+	}
+
+	// Delete the items that are no longer wanted
+	for sk := range existing {
+		if kept[sk] {
+			continue
+		}
+		items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+			TableName: aws.String(s.table),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: partition},
+				"sk": &types.AttributeValueMemberS{Value: sk},
+			},
+		}})
+	}
+
+	if len(items) > maxTransactItems {
+		return fmt.Errorf("too many items to replace at once: %d", len(wanted))
 	}
 
-	return options, nil
+	_, err = s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for i, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) != "ConditionalCheckFailed" {
+				continue
+			}
+			if i == 0 {
+				return errBallotConflict
+			}
+			return fmt.Errorf("question not found: %s", questionID)
+		}
+		return errBallotConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace partition %s in DynamoDB: %w", partition, err)
+	}
+
+	return nil
+}
+
+// Veto Operations
+
+// ReplaceVetoes swaps all of a participant's vetoes on a question for the
+// given ones in a single transaction, guarded by a ballot box of their own
+// the way ReplaceBallots is
+func (s *DynamoStore) ReplaceVetoes(ctx context.Context, questionID string, participantID string, vetoes []models.Veto) error {
+	if err := checkVetoes(questionID, participantID, vetoes); err != nil {
+		return err
+	}
+
+	var items []partitionItem
+	for _, veto := range vetoes {
+		veto = models.NewVeto(questionID, models.NewParticipant(participantID, veto.ParticipantName), veto.OptionID)
+
+		item, err := attributevalue.MarshalMap(veto)
+		if err != nil {
+			return fmt.Errorf("failed to marshal veto: %w", err)
+		}
+		items = append(items, partitionItem{SK: veto.SK, OptionID: veto.OptionID, Item: item})
+	}
+
+	for attempt := 0; attempt < maxBallotAttempts; attempt++ {
+		err := s.replacePartition(ctx, models.VetoPartition(questionID, participantID), questionID, items)
+		if !errors.Is(err, errBallotConflict) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to replace vetoes after %d attempts: %w", maxBallotAttempts, ErrConflict)
+}
+
+// versionCondition returns the condition of a write that only applies to an
+// item still at the given version, with the values it refers to and
+// :nextVersion, the version the write stores. Items stored before they had
+// versions count as version 0.
+func versionCondition(version int) (string, map[string]types.AttributeValue) {
+	condition := "version = :version"
+	if version == 0 {
+		condition = "attribute_exists(pk) AND (attribute_not_exists(version) OR version = :version)"
+	}
+
+	return condition, map[string]types.AttributeValue{
+		":version":     &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		":nextVersion": &types.AttributeValueMemberN{Value: strconv.Itoa(version + 1)},
+	}
+}
+
+// eventKey returns the primary key of an event item
+func eventKey(eventID string) map[string]types.AttributeValue {
+	key := string(models.EventEntity) + "#" + eventID
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: key},
+		"sk": &types.AttributeValueMemberS{Value: key},
+	}
+}
+
+// optionKey returns the primary key of an option item
+func optionKey(optionID string, questionID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: string(models.OptionEntity) + "#" + optionID},
+		"sk": &types.AttributeValueMemberS{Value: string(models.QuestionEntity) + "#" + questionID},
+	}
+}
+
+// checkOptions checks that every item is on an option of the question that
+// is not being deleted
+func (s *DynamoStore) checkOptions(ctx context.Context, questionID string, items []partitionItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	options, err := s.queryKeys(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("QuestionIDIndex"),
+		KeyConditionExpression: aws.String("question_id = :questionID AND begins_with(pk, :option)"),
+		FilterExpression:       aws.String("attribute_not_exists(deleting)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":questionID": &types.AttributeValueMemberS{Value: questionID},
+			":option":     &types.AttributeValueMemberS{Value: string(models.OptionEntity) + "#"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query options of question %s from DynamoDB: %w", questionID, err)
+	}
+
+	exists := make(map[string]bool, len(options))
+	for _, key := range options {
+		exists[keyID(key, models.OptionEntity)] = true
+	}
+	for _, item := range items {
+		if !exists[item.OptionID] {
+			return fmt.Errorf("option not found: %s", item.OptionID)
+		}
+	}
+	return nil
 }
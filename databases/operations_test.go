@@ -0,0 +1,193 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// createVotedEvent creates an event with a few questions, each with a few
+// options and a ballot for each of them
+func createVotedEvent(tb testing.TB, store *DynamoStore) string {
+	tb.Helper()
+	ctx := context.Background()
+
+	event := models.NewEvent("voted-event", "Voted")
+	if err := store.CreateEvent(ctx, event); err != nil {
+		tb.Fatalf("failed to create event: %v", err)
+	}
+	for q := 0; q < 3; q++ {
+		question := models.NewQuestion(fmt.Sprintf("question-%d", q), event.ID, "Which?")
+		if err := store.AddQuestion(ctx, event.ID, question); err != nil {
+			tb.Fatalf("failed to add question: %v", err)
+		}
+		for o := 0; o < 4; o++ {
+			option := models.NewOption(fmt.Sprintf("option-%d-%d", q, o), question.ID, "Option")
+			if err := store.AddOption(ctx, question.ID, option); err != nil {
+				tb.Fatalf("failed to add option: %v", err)
+			}
+			participant := models.NewParticipant(fmt.Sprintf("participant-%d", o), "")
+			ballot := models.NewBallot(question.ID, participant, option.ID)
+			if err := store.ReplaceBallots(ctx, question.ID, participant.ID, []models.Ballot{ballot}); err != nil {
+				tb.Fatalf("failed to vote: %v", err)
+			}
+		}
+	}
+
+	return event.ID
+}
+
+// getEventPerQuestion reads an event the way GetEvent did before the event
+// item joined its item collection: the event item, then its questions, then
+// the options, ballots and vetoes of each question in turn. It is kept as
+// the baseline GetEvent is measured against.
+func getEventPerQuestion(ctx context.Context, store *DynamoStore, eventID string) (*models.Event, error) {
+	key := string(models.EventEntity) + "#" + eventID
+	result, err := store.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.table),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: key},
+			"sk": &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil || result.Item == nil {
+		return nil, err
+	}
+	var event models.Event
+	if err := attributevalue.UnmarshalMap(result.Item, &event); err != nil {
+		return nil, err
+	}
+
+	keys, err := store.eventQuestionKeys(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		question, err := store.GetQuestion(ctx, keyID(key, models.QuestionEntity))
+		if err != nil {
+			return nil, err
+		}
+		event.Questions = append(event.Questions, *question)
+	}
+	return &event, nil
+}
+
+func TestGetEventQueriesOnce(t *testing.T) {
+	store, client := newInstrumentedStore(t)
+	eventID := createVotedEvent(t, store)
+	ctx := context.Background()
+
+	client.reset()
+	event, err := store.GetEvent(ctx, eventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent = %v, %v, want the event", event, err)
+	}
+	if len(event.Questions) != 3 {
+		t.Errorf("event has %d questions, want 3", len(event.Questions))
+	}
+	for _, question := range event.Questions {
+		for _, option := range question.Options {
+			if option.Votes != 1 {
+				t.Errorf("option %s has %d votes, want 1", option.ID, option.Votes)
+			}
+		}
+	}
+	if calls := client.total(); calls != 1 {
+		t.Errorf("GetEvent made %d calls, want 1: %v", calls, client.calls)
+	}
+
+	// Reading question by question took a call for the event item, one for
+	// its questions and more for each question
+	client.reset()
+	if _, err := getEventPerQuestion(ctx, store, eventID); err != nil {
+		t.Fatalf("failed to read event question by question: %v", err)
+	}
+	if calls := client.total(); calls < 2+len(event.Questions) {
+		t.Errorf("reading question by question made %d calls, want at least %d", calls, 2+len(event.Questions))
+	}
+}
+
+// BenchmarkGetEvent compares GetEvent with reading the same event question
+// by question, reporting the calls each makes to DynamoDB
+func BenchmarkGetEvent(b *testing.B) {
+	store, client := newInstrumentedStore(b)
+	eventID := createVotedEvent(b, store)
+	ctx := context.Background()
+
+	reads := []struct {
+		name string
+		read func(ctx context.Context, store *DynamoStore, eventID string) (*models.Event, error)
+	}{
+		{"Query", func(ctx context.Context, store *DynamoStore, eventID string) (*models.Event, error) {
+			return store.GetEvent(ctx, eventID)
+		}},
+		{"PerQuestion", getEventPerQuestion},
+	}
+	for _, read := range reads {
+		b.Run(read.name, func(b *testing.B) {
+			client.reset()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := read.read(ctx, store, eventID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(client.total())/float64(b.N), "calls/op")
+		})
+	}
+}
+
+// startKeyClient is a DynamoDB client that answers every query with no items,
+// remembering the key the last one started after
+type startKeyClient struct {
+	dynamoClient
+	queries int
+	start   map[string]types.AttributeValue
+}
+
+func (c *startKeyClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.queries++
+	c.start = input.ExclusiveStartKey
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func TestListEventsPageChecksCursor(t *testing.T) {
+	client := &startKeyClient{}
+	store := &DynamoStore{client: client, table: "planzoco"}
+	ctx := context.Background()
+
+	for name, after := range map[string]map[string]string{
+		"missing sk":     {"entity_type": "EVENT", "pk": "EVENT#a"},
+		"extra key":      {"entity_type": "EVENT", "pk": "EVENT#a", "sk": "EVENT#a", "event_id": "a"},
+		"question":       {"entity_type": "QUESTION", "pk": "QUESTION#a", "sk": "EVENT#a"},
+		"empty key":      {"entity_type": "EVENT", "pk": "", "sk": "EVENT#a"},
+		"no entity type": {"pk": "EVENT#a", "sk": "EVENT#a", "id": "a"},
+	} {
+		if _, _, err := store.ListEventsPage(ctx, 10, encodeCursor(after)); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor with %s returned %v, want ErrInvalidCursor", name, err)
+		}
+	}
+	if client.queries != 0 {
+		t.Errorf("invalid cursors made %d queries, want none", client.queries)
+	}
+
+	after := map[string]string{"entity_type": "EVENT", "pk": "EVENT#a", "sk": "EVENT#a"}
+	if _, _, err := store.ListEventsPage(ctx, 10, encodeCursor(after)); err != nil {
+		t.Fatalf("valid cursor returned %v", err)
+	}
+	for name, value := range after {
+		if got, ok := client.start[name].(*types.AttributeValueMemberS); !ok || got.Value != value {
+			t.Errorf("query started after %s = %v, want %q", name, client.start[name], value)
+		}
+	}
+}
@@ -0,0 +1,914 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// DefaultSQLitePath is used if no SQLite database file is specified
+	DefaultSQLitePath = "planzoco.db"
+
+	// optionColumns selects an option along with its vote count, which
+	// leaves out availability answers of "no", and its veto count
+	questionColumns = "id, event_id, text, type, max_selections, scale_min, scale_max, win_by, tie_break, min_participants, majority, majority_percent, veto_limit, hide_results, results_revealed, locked, closes_at, decision, version"
+	optionColumns   = "id, question_id, text, slot_start, slot_end, slot_time_zone, pending, created_at, version, (SELECT COUNT(*) FROM ballots WHERE ballots.option_id = options.id AND ballots.availability <> 'no'), (SELECT COUNT(*) FROM vetoes WHERE vetoes.option_id = options.id)"
+	ballotColumns   = "question_id, option_id, participant_id, participant_name, rank, availability, score"
+	vetoColumns     = "question_id, option_id, participant_id, participant_name"
+)
+
+// SQLStore is the Store backed by a relational database. Events, questions
+// and options live in their own tables, linked by foreign keys that cascade
+// on delete.
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// GetSQLitePath returns the SQLite database file based on environment variables or defaults
+func GetSQLitePath() string {
+	if path := os.Getenv("SQLITE_PATH"); path != "" {
+		return path
+	}
+	return DefaultSQLitePath
+}
+
+// GetDatabaseURL returns the PostgreSQL connection string
+func GetDatabaseURL() string {
+	return os.Getenv("DATABASE_URL")
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database at path and migrates it
+func NewSQLiteStore(path string) (*SQLStore, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite allows a single writer, so serialize access rather than fail with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	store, err := newSQLStore(db, BackendSQLite)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("SQLite store initialized, using database: %s", path)
+
+	return store, nil
+}
+
+// NewPostgresStore connects to the PostgreSQL database at url and migrates it
+func NewPostgresStore(url string) (*SQLStore, error) {
+	if url == "" {
+		return nil, errors.New("DATABASE_URL must be set to use the postgres backend")
+	}
+
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
+	}
+
+	store, err := newSQLStore(db, BackendPostgres)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("PostgreSQL store initialized")
+
+	return store, nil
+}
+
+func newSQLStore(db *sql.DB, dialect string) (*SQLStore, error) {
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect, err)
+	}
+
+	store := &SQLStore{db: db, dialect: dialect}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close closes the underlying database connection
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Event Operations
+
+// CreateEvent inserts a new event
+func (s *SQLStore) CreateEvent(ctx context.Context, event models.Event) error {
+	_, err := s.db.ExecContext(ctx, s.rebind("INSERT INTO events (id, name, admin_token, moderate_options, closes_at, created_at) VALUES (?, ?, ?, ?, ?, ?)"),
+		event.ID, event.Name, event.AdminToken, event.ModerateOptions, timeColumn(event.ClosesAt), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	return nil
+}
+
+// GetEvent retrieves an event by ID along with its questions and options
+func (s *SQLStore) GetEvent(ctx context.Context, eventID string) (*models.Event, error) {
+	var name, adminToken string
+	var moderateOptions bool
+	var closesAt sql.NullTime
+	var version int
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT name, admin_token, moderate_options, closes_at, version FROM events WHERE id = ?"), eventID).Scan(&name, &adminToken, &moderateOptions, &closesAt, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	event := models.NewEvent(eventID, name)
+	event.AdminToken = adminToken
+	event.ModerateOptions = moderateOptions
+	event.ClosesAt = timeValue(closesAt)
+	event.Version = version
+
+	questions, err := s.GetQuestionsByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get questions for event: %w", err)
+	}
+
+	event.Questions = questions
+	return &event, nil
+}
+
+// UpdateEvent updates the name, moderation and close time of an existing
+// event, unless it has changed since event was read
+func (s *SQLStore) UpdateEvent(ctx context.Context, event models.Event) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE events SET name = ?, moderate_options = ?, closes_at = ?, version = version + 1 WHERE id = ? AND version = ?"), event.Name, event.ModerateOptions, timeColumn(event.ClosesAt), event.ID, event.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+
+	return s.requireVersion(ctx, result, "events", "event not found for update: %s", event.ID)
+}
+
+// SetAdminToken gives an event without an admin token the token, reporting
+// false if it already had one
+func (s *SQLStore) SetAdminToken(ctx context.Context, eventID string, token string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE events SET admin_token = ? WHERE id = ? AND admin_token = ''"), token, eventID)
+	if err != nil {
+		return false, fmt.Errorf("failed to set admin token: %w", err)
+	}
+
+	set, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to set admin token: %w", err)
+	}
+	if set == 0 {
+		var exists int
+		err := s.db.QueryRowContext(ctx, s.rebind("SELECT 1 FROM events WHERE id = ?"), eventID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("event not found for admin token: %s", eventID)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to set admin token: %w", err)
+		}
+	}
+	return set > 0, nil
+}
+
+// DeleteEvent deletes an event, cascading to its questions and options
+func (s *SQLStore) DeleteEvent(ctx context.Context, eventID string) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM events WHERE id = ?"), eventID); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	return nil
+}
+
+// ListEvents retrieves all events, without their questions
+func (s *SQLStore) ListEvents(ctx context.Context) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, admin_token, moderate_options, closes_at, version FROM events ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var id, name, adminToken string
+		var moderateOptions bool
+		var closesAt sql.NullTime
+		var version int
+		if err := rows.Scan(&id, &name, &adminToken, &moderateOptions, &closesAt, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event := models.NewEvent(id, name)
+		event.AdminToken = adminToken
+		event.ModerateOptions = moderateOptions
+		event.ClosesAt = timeValue(closesAt)
+		event.Version = version
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+
+	return events, nil
+}
+
+// sqlCursor is the last event on a page, by the order events are listed in
+type sqlCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// ListEventsPage retrieves a page of events, without their questions
+func (s *SQLStore) ListEventsPage(ctx context.Context, limit int, cursor string) ([]models.Event, string, error) {
+	after := sqlCursor{}
+	if cursor != "" {
+		if err := decodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// One more than asked for tells whether there is another page
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT id, name, admin_token, moderate_options, closes_at, version, created_at FROM events
+		WHERE created_at > ? OR (created_at = ? AND id > ?)
+		ORDER BY created_at, id LIMIT ?`), after.CreatedAt, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	var created []time.Time
+	for rows.Next() {
+		var id, name, adminToken string
+		var moderateOptions bool
+		var closesAt sql.NullTime
+		var createdAt time.Time
+		var version int
+		if err := rows.Scan(&id, &name, &adminToken, &moderateOptions, &closesAt, &version, &createdAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan event: %w", err)
+		}
+		event := models.NewEvent(id, name)
+		event.AdminToken = adminToken
+		event.ModerateOptions = moderateOptions
+		event.ClosesAt = timeValue(closesAt)
+		event.Version = version
+		events = append(events, event)
+		created = append(created, createdAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to query events: %w", err)
+	}
+
+	var next string
+	if len(events) > limit {
+		events = events[:limit]
+		next = encodeCursor(sqlCursor{CreatedAt: created[limit-1], ID: events[limit-1].ID})
+	}
+
+	return events, next, nil
+}
+
+// Question Operations
+
+// AddQuestion inserts a new question for an event
+func (s *SQLStore) AddQuestion(ctx context.Context, eventID string, question models.Question) error {
+	_, err := s.db.ExecContext(ctx, s.rebind("INSERT INTO questions (id, event_id, text, type, max_selections, scale_min, scale_max, win_by, tie_break, min_participants, majority, majority_percent, veto_limit, hide_results, results_revealed, locked, closes_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		question.ID, eventID, question.Text, question.Kind(), question.MaxSelections, question.ScaleMin, question.ScaleMax, string(question.WinBy), string(question.TieBreak), question.MinParticipants, string(question.Majority), question.MajorityPercent, question.VetoLimit, question.HideResults, question.ResultsRevealed, question.Locked, timeColumn(question.ClosesAt), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert question: %w", err)
+	}
+
+	return nil
+}
+
+// GetQuestion retrieves a question by ID along with its options
+func (s *SQLStore) GetQuestion(ctx context.Context, questionID string) (*models.Question, error) {
+	question, err := scanQuestion(s.db.QueryRowContext(ctx, s.rebind("SELECT "+questionColumns+" FROM questions WHERE id = ?"), questionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question: %w", err)
+	}
+
+	options, err := s.GetOptionsByQuestionID(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options for question: %w", err)
+	}
+
+	ballots, err := s.GetBallotsByQuestionID(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ballots for question: %w", err)
+	}
+
+	vetoes, err := s.GetVetoesByQuestionID(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vetoes for question: %w", err)
+	}
+
+	question.Options = options
+	question.Ballots = ballots
+	question.Vetoes = vetoes
+	return &question, nil
+}
+
+// GetQuestionWithEvent retrieves a question with its associated event
+func (s *SQLStore) GetQuestionWithEvent(ctx context.Context, questionID string) (*models.Question, *models.Event, error) {
+	question, err := s.GetQuestion(ctx, questionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if question == nil {
+		return nil, nil, nil
+	}
+
+	event, err := s.GetEvent(ctx, question.EventID)
+	if err != nil {
+		return question, nil, err
+	}
+
+	return question, event, nil
+}
+
+// UpdateQuestion updates the text and settings of an existing question. Its
+// type and scale are fixed once it exists, and its decision is only set by
+// CloseQuestion. Nothing is written if it has changed since question was read.
+func (s *SQLStore) UpdateQuestion(ctx context.Context, question models.Question) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE questions SET text = ?, max_selections = ?, win_by = ?, tie_break = ?, min_participants = ?, majority = ?, majority_percent = ?, veto_limit = ?, hide_results = ?, results_revealed = ?, locked = ?, closes_at = ?, version = version + 1 WHERE id = ? AND version = ?"),
+		question.Text, question.MaxSelections, string(question.WinBy), string(question.TieBreak), question.MinParticipants, string(question.Majority), question.MajorityPercent, question.VetoLimit, question.HideResults, question.ResultsRevealed, question.Locked, timeColumn(question.ClosesAt), question.ID, question.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update question: %w", err)
+	}
+
+	return s.requireVersion(ctx, result, "questions", "question not found for update: %s", question.ID)
+}
+
+// CloseQuestion stores the decision of a question unless it already has one
+func (s *SQLStore) CloseQuestion(ctx context.Context, questionID string, decision models.Decision) (bool, error) {
+	encoded, err := json.Marshal(decision)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode decision: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE questions SET decision = ?, version = version + 1 WHERE id = ? AND decision = ''"), string(encoded), questionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to close question: %w", err)
+	}
+
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to close question: %w", err)
+	}
+	return closed > 0, nil
+}
+
+// UpdateDecision replaces the decision of a decided question
+func (s *SQLStore) UpdateDecision(ctx context.Context, questionID string, decision models.Decision) error {
+	encoded, err := json.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("failed to encode decision: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE questions SET decision = ?, version = version + 1 WHERE id = ? AND decision <> ''"), string(encoded), questionID)
+	if err != nil {
+		return fmt.Errorf("failed to update decision: %w", err)
+	}
+
+	return requireRow(result, "decided question not found: %s", questionID)
+}
+
+// DeleteQuestion deletes a question, cascading to its options
+func (s *SQLStore) DeleteQuestion(ctx context.Context, questionID string) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM questions WHERE id = ?"), questionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete question: %w", err)
+	}
+
+	return requireRow(result, "question not found for deletion: %s", questionID)
+}
+
+// GetQuestionsByEventID retrieves all questions for a given event ID
+func (s *SQLStore) GetQuestionsByEventID(ctx context.Context, eventID string) ([]models.Question, error) {
+	return s.queryQuestions(ctx, s.rebind("SELECT "+questionColumns+" FROM questions WHERE event_id = ? ORDER BY created_at, id"), eventID)
+}
+
+// ListDueQuestionIDs retrieves the IDs of the undecided questions whose
+// close time, or their event's, has passed. Only questions with a close time
+// on either are read, and which of those have passed is worked out here, as
+// the dialects compare times differently.
+func (s *SQLStore) ListDueQuestionIDs(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT q.id, q.closes_at, e.closes_at FROM questions q JOIN events e ON e.id = q.event_id
+		WHERE q.decision = '' AND (q.closes_at IS NOT NULL OR e.closes_at IS NOT NULL)
+		ORDER BY q.created_at, q.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query questions with close times: %w", err)
+	}
+	defer rows.Close()
+
+	var questionIDs []string
+	for rows.Next() {
+		var question models.Question
+		var event models.Event
+		var questionClosesAt, eventClosesAt sql.NullTime
+		if err := rows.Scan(&question.ID, &questionClosesAt, &eventClosesAt); err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		question.ClosesAt = timeValue(questionClosesAt)
+		event.ClosesAt = timeValue(eventClosesAt)
+		if question.DueToClose(&event, now) {
+			questionIDs = append(questionIDs, question.ID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query questions with close times: %w", err)
+	}
+
+	return questionIDs, nil
+}
+
+// Option Operations
+
+// AddOption inserts a new option for a question
+func (s *SQLStore) AddOption(ctx context.Context, questionID string, option models.Option) error {
+	start, end, timeZone := slotColumns(option.Slot)
+	_, err := s.db.ExecContext(ctx, s.rebind("INSERT INTO options (id, question_id, text, slot_start, slot_end, slot_time_zone, pending, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		option.ID, questionID, option.Text, start, end, timeZone, option.Pending, createdAt(option.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert option: %w", err)
+	}
+
+	return nil
+}
+
+// GetOption retrieves an option by ID
+func (s *SQLStore) GetOption(ctx context.Context, optionID string) (*models.Option, error) {
+	options, err := s.queryOptions(ctx, s.rebind("SELECT "+optionColumns+" FROM options WHERE id = ?"), optionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	return &options[0], nil
+}
+
+// UpdateOption updates the text, slot and approval of an existing option,
+// unless it has changed since option was read
+func (s *SQLStore) UpdateOption(ctx context.Context, option models.Option) error {
+	start, end, timeZone := slotColumns(option.Slot)
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE options SET text = ?, slot_start = ?, slot_end = ?, slot_time_zone = ?, pending = ?, version = version + 1 WHERE id = ? AND version = ?"),
+		option.Text, start, end, timeZone, option.Pending, option.ID, option.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update option: %w", err)
+	}
+
+	return s.requireVersion(ctx, result, "options", "option not found for update: %s", option.ID)
+}
+
+// DeleteOption deletes an option by ID
+func (s *SQLStore) DeleteOption(ctx context.Context, optionID string) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM options WHERE id = ?"), optionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete option: %w", err)
+	}
+
+	return requireRow(result, "option not found for deletion: %s", optionID)
+}
+
+// GetOptionsByQuestionID retrieves all options for a given question ID
+func (s *SQLStore) GetOptionsByQuestionID(ctx context.Context, questionID string) ([]models.Option, error) {
+	return s.queryOptions(ctx, s.rebind("SELECT "+optionColumns+" FROM options WHERE question_id = ? ORDER BY created_at, id"), questionID)
+}
+
+// Participant Operations
+
+// SaveParticipant creates or replaces a participant
+func (s *SQLStore) SaveParticipant(ctx context.Context, participant models.Participant) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO participants (id, name, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name`),
+		participant.ID, participant.Name, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save participant: %w", err)
+	}
+
+	return nil
+}
+
+// GetParticipant retrieves a participant by ID
+func (s *SQLStore) GetParticipant(ctx context.Context, participantID string) (*models.Participant, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT name FROM participants WHERE id = ?"), participantID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+
+	participant := models.NewParticipant(participantID, name)
+	return &participant, nil
+}
+
+// Ballot Operations
+
+// GetBallotsByQuestionID retrieves every ballot cast on a question
+func (s *SQLStore) GetBallotsByQuestionID(ctx context.Context, questionID string) ([]models.Ballot, error) {
+	return s.queryBallots(ctx, s.rebind("SELECT "+ballotColumns+" FROM ballots WHERE question_id = ? ORDER BY created_at"), questionID)
+}
+
+// ReplaceBallots swaps all of a participant's ballots on a question for the
+// given ones inside a single transaction
+func (s *SQLStore) ReplaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error {
+	if err := checkBallots(questionID, participantID, ballots); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start ballot transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.dialect == BackendPostgres {
+		// Serialize concurrent submissions from the same participant
+		_, err := tx.ExecContext(ctx, "SELECT id FROM participants WHERE id = $1 FOR UPDATE", participantID)
+		if err != nil {
+			return fmt.Errorf("failed to lock participant: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, s.rebind("DELETE FROM ballots WHERE question_id = ? AND participant_id = ?"), questionID, participantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete previous ballots: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, ballot := range ballots {
+		// Only insert the ballot if the option really belongs to the question
+		result, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO ballots (question_id, option_id, participant_id, participant_name, rank, availability, score, created_at)
+			SELECT ?, ?, ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM options WHERE id = ? AND question_id = ?)`),
+			questionID, ballot.OptionID, participantID, ballot.ParticipantName, ballot.Rank, string(ballot.Availability), ballot.Score, now, ballot.OptionID, questionID)
+		if err != nil {
+			return fmt.Errorf("failed to insert ballot: %w", err)
+		}
+		if err := requireRow(result, "option not found: %s", ballot.OptionID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ballots: %w", err)
+	}
+
+	return nil
+}
+
+// Veto Operations
+
+// GetVetoesByQuestionID retrieves every veto cast on a question
+func (s *SQLStore) GetVetoesByQuestionID(ctx context.Context, questionID string) ([]models.Veto, error) {
+	return s.queryVetoes(ctx, s.rebind("SELECT "+vetoColumns+" FROM vetoes WHERE question_id = ? ORDER BY created_at"), questionID)
+}
+
+// ReplaceVetoes swaps all of a participant's vetoes on a question for the
+// given ones inside a single transaction
+func (s *SQLStore) ReplaceVetoes(ctx context.Context, questionID string, participantID string, vetoes []models.Veto) error {
+	if err := checkVetoes(questionID, participantID, vetoes); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start veto transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.dialect == BackendPostgres {
+		// Serialize concurrent submissions from the same participant
+		_, err := tx.ExecContext(ctx, "SELECT id FROM participants WHERE id = $1 FOR UPDATE", participantID)
+		if err != nil {
+			return fmt.Errorf("failed to lock participant: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, s.rebind("DELETE FROM vetoes WHERE question_id = ? AND participant_id = ?"), questionID, participantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete previous vetoes: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, veto := range vetoes {
+		// Only insert the veto if the option really belongs to the question
+		result, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO vetoes (question_id, option_id, participant_id, participant_name, created_at)
+			SELECT ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM options WHERE id = ? AND question_id = ?)`),
+			questionID, veto.OptionID, participantID, veto.ParticipantName, now, veto.OptionID, questionID)
+		if err != nil {
+			return fmt.Errorf("failed to insert veto: %w", err)
+		}
+		if err := requireRow(result, "option not found: %s", veto.OptionID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit vetoes: %w", err)
+	}
+
+	return nil
+}
+
+// queryQuestions runs a query selecting questionColumns from questions,
+// and attaches the options, ballots and vetoes of every question it returns
+func (s *SQLStore) queryQuestions(ctx context.Context, query string, args ...any) ([]models.Question, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []models.Question
+	for rows.Next() {
+		question, err := scanQuestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		questions = append(questions, question)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query questions: %w", err)
+	}
+
+	if len(questions) == 0 {
+		return questions, nil
+	}
+
+	// Fetch the options of all questions in one go rather than one query per question
+	ids := make([]any, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+	options, err := s.queryOptions(ctx, s.rebind("SELECT "+optionColumns+" FROM options WHERE question_id IN ("+placeholders(len(ids))+") ORDER BY created_at, id"), ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ballots, err := s.queryBallots(ctx, s.rebind("SELECT "+ballotColumns+" FROM ballots WHERE question_id IN ("+placeholders(len(ids))+") ORDER BY created_at"), ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	vetoes, err := s.queryVetoes(ctx, s.rebind("SELECT "+vetoColumns+" FROM vetoes WHERE question_id IN ("+placeholders(len(ids))+") ORDER BY created_at"), ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsByQuestion := make(map[string][]models.Option)
+	for _, option := range options {
+		optionsByQuestion[option.QuestionID] = append(optionsByQuestion[option.QuestionID], option)
+	}
+	ballotsByQuestion := make(map[string][]models.Ballot)
+	for _, ballot := range ballots {
+		ballotsByQuestion[ballot.QuestionID] = append(ballotsByQuestion[ballot.QuestionID], ballot)
+	}
+	vetoesByQuestion := make(map[string][]models.Veto)
+	for _, veto := range vetoes {
+		vetoesByQuestion[veto.QuestionID] = append(vetoesByQuestion[veto.QuestionID], veto)
+	}
+	for i := range questions {
+		questions[i].Options = optionsByQuestion[questions[i].ID]
+		questions[i].Ballots = ballotsByQuestion[questions[i].ID]
+		questions[i].Vetoes = vetoesByQuestion[questions[i].ID]
+	}
+
+	return questions, nil
+}
+
+// queryOptions runs a query selecting optionColumns from options
+func (s *SQLStore) queryOptions(ctx context.Context, query string, args ...any) ([]models.Option, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []models.Option
+	for rows.Next() {
+		var id, questionID, text, timeZone string
+		var start, end sql.NullTime
+		var pending bool
+		var created time.Time
+		var version, votes, vetoes int
+		if err := rows.Scan(&id, &questionID, &text, &start, &end, &timeZone, &pending, &created, &version, &votes, &vetoes); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		option := models.NewOption(id, questionID, text)
+		if start.Valid && end.Valid {
+			option.Slot = &models.Slot{Start: start.Time, End: end.Time, TimeZone: timeZone}
+		}
+		option.Pending = pending
+		option.Votes = votes
+		option.Vetoes = vetoes
+		option.CreatedAt = created.UTC()
+		option.Version = version
+		options = append(options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query options: %w", err)
+	}
+
+	return options, nil
+}
+
+// scanQuestion reads a row selecting questionColumns
+func scanQuestion(row interface{ Scan(...any) error }) (models.Question, error) {
+	var id, eventID, text, decision string
+	var questionType models.QuestionType
+	var maxSelections, scaleMin, scaleMax, minParticipants, majorityPercent, vetoLimit, version int
+	var winBy models.ScoreStatistic
+	var tieBreak models.TieBreak
+	var majority models.Majority
+	var hideResults, resultsRevealed, locked bool
+	var closesAt sql.NullTime
+	if err := row.Scan(&id, &eventID, &text, &questionType, &maxSelections, &scaleMin, &scaleMax, &winBy, &tieBreak, &minParticipants, &majority, &majorityPercent, &vetoLimit, &hideResults, &resultsRevealed, &locked, &closesAt, &decision, &version); err != nil {
+		return models.Question{}, err
+	}
+
+	question := models.NewQuestion(id, eventID, text)
+	question.Type = questionType
+	question.MaxSelections = maxSelections
+	question.ScaleMin, question.ScaleMax = scaleMin, scaleMax
+	question.WinBy = winBy
+	question.TieBreak = tieBreak
+	question.MinParticipants = minParticipants
+	question.Majority, question.MajorityPercent = majority, majorityPercent
+	question.VetoLimit = vetoLimit
+	question.HideResults, question.ResultsRevealed = hideResults, resultsRevealed
+	question.Locked = locked
+	question.ClosesAt = timeValue(closesAt)
+	question.Version = version
+	if decision != "" {
+		question.Decision = &models.Decision{}
+		if err := json.Unmarshal([]byte(decision), question.Decision); err != nil {
+			return models.Question{}, fmt.Errorf("invalid decision: %w", err)
+		}
+	}
+	return question, nil
+}
+
+// timeColumn returns the value of a nullable time column, in UTC
+func timeColumn(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// timeValue returns the time read from a nullable time column, nil for NULL
+func timeValue(column sql.NullTime) *time.Time {
+	if !column.Valid {
+		return nil
+	}
+	t := column.Time.UTC()
+	return &t
+}
+
+// slotColumns returns the values of an option's slot columns, NULL for an
+// option without a slot. Times are stored in UTC; the slot's time zone is
+// kept alongside to show them in.
+func slotColumns(slot *models.Slot) (sql.NullTime, sql.NullTime, string) {
+	if slot == nil {
+		return sql.NullTime{}, sql.NullTime{}, ""
+	}
+	return sql.NullTime{Time: slot.Start.UTC(), Valid: true}, sql.NullTime{Time: slot.End.UTC(), Valid: true}, slot.TimeZone
+}
+
+// queryBallots runs a query selecting ballotColumns from ballots
+func (s *SQLStore) queryBallots(ctx context.Context, query string, args ...any) ([]models.Ballot, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ballots: %w", err)
+	}
+	defer rows.Close()
+
+	var ballots []models.Ballot
+	for rows.Next() {
+		var questionID, optionID string
+		var participant models.Participant
+		var rank, score int
+		var availability string
+		if err := rows.Scan(&questionID, &optionID, &participant.ID, &participant.Name, &rank, &availability, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan ballot: %w", err)
+		}
+		ballot := models.NewBallot(questionID, participant, optionID)
+		ballot.Rank = rank
+		ballot.Availability = models.Availability(availability)
+		ballot.Score = score
+		ballots = append(ballots, ballot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query ballots: %w", err)
+	}
+
+	return ballots, nil
+}
+
+// queryVetoes runs a query selecting vetoColumns from vetoes
+func (s *SQLStore) queryVetoes(ctx context.Context, query string, args ...any) ([]models.Veto, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vetoes: %w", err)
+	}
+	defer rows.Close()
+
+	var vetoes []models.Veto
+	for rows.Next() {
+		var questionID, optionID string
+		var participant models.Participant
+		if err := rows.Scan(&questionID, &optionID, &participant.ID, &participant.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan veto: %w", err)
+		}
+		vetoes = append(vetoes, models.NewVeto(questionID, participant, optionID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query vetoes: %w", err)
+	}
+
+	return vetoes, nil
+}
+
+// rebind rewrites ? placeholders into the numbered form PostgreSQL expects
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != BackendPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// requireRow returns a not found error if the statement affected no rows
+func requireRow(result sql.Result, format string, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf(format, id)
+	}
+	return nil
+}
+
+// requireVersion is requireRow for an update of the row in table that only
+// applies to the version it was based on. It returns ErrConflict if the row
+// is there but has another version.
+func (s *SQLStore) requireVersion(ctx context.Context, result sql.Result, table string, format string, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, s.rebind("SELECT 1 FROM "+table+" WHERE id = ?"), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf(format, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for a conflicting update: %w", err)
+	}
+	return ErrConflict
+}
@@ -0,0 +1,240 @@
+package databases
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+)
+
+const (
+	// BackendDynamoDB stores everything in a single DynamoDB table
+	BackendDynamoDB = "dynamodb"
+	// BackendMemory keeps everything in process memory and loses it on restart
+	BackendMemory = "memory"
+	// BackendSQLite stores everything in an embedded SQLite database file
+	BackendSQLite = "sqlite"
+	// BackendPostgres stores everything in a PostgreSQL server
+	BackendPostgres = "postgres"
+	// DefaultBackend is used if no storage backend is specified
+	DefaultBackend = BackendDynamoDB
+)
+
+// Store is the persistence layer used by the handlers. Every storage backend
+// implements the full set of event, question, option, ballot and veto
+// operations.
+//
+// Options returned by a Store always have Votes and Vetoes counted from their
+// ballots and vetoes and come in the order they were created, and questions
+// come with their Ballots and Vetoes attached.
+//
+// Events, questions and options carry a Version that goes up by one whenever
+// they are written. UpdateEvent, UpdateQuestion and UpdateOption only write
+// an item whose stored Version is still the one passed in, so an edit based
+// on a stale copy returns ErrConflict instead of overwriting someone else's.
+type Store interface {
+	// Event operations
+	CreateEvent(ctx context.Context, event models.Event) error
+	GetEvent(ctx context.Context, eventID string) (*models.Event, error)
+	UpdateEvent(ctx context.Context, event models.Event) error
+	DeleteEvent(ctx context.Context, eventID string) error
+	// SetAdminToken gives an event created before admin tokens existed the
+	// token, reporting false if it already had one, in which case that is kept
+	SetAdminToken(ctx context.Context, eventID string, token string) (bool, error)
+	// ListEvents retrieves every event, without its questions
+	ListEvents(ctx context.Context) ([]models.Event, error)
+	// ListEventsPage retrieves at most limit events, without their questions,
+	// starting after the page that returned cursor, or at the beginning if
+	// cursor is empty. It also returns the cursor of the next page, empty once
+	// there are no more.
+	ListEventsPage(ctx context.Context, limit int, cursor string) ([]models.Event, string, error)
+
+	// Question operations
+	AddQuestion(ctx context.Context, eventID string, question models.Question) error
+	GetQuestion(ctx context.Context, questionID string) (*models.Question, error)
+	GetQuestionWithEvent(ctx context.Context, questionID string) (*models.Question, *models.Event, error)
+	UpdateQuestion(ctx context.Context, question models.Question) error
+	// CloseQuestion stores a question's frozen result, reporting false if the
+	// question was already decided, in which case its decision is kept
+	CloseQuestion(ctx context.Context, questionID string, decision models.Decision) (bool, error)
+	// UpdateDecision replaces the decision of a decided question, such as
+	// when the organizer settles a tie
+	UpdateDecision(ctx context.Context, questionID string, decision models.Decision) error
+	DeleteQuestion(ctx context.Context, questionID string) error
+	GetQuestionsByEventID(ctx context.Context, eventID string) ([]models.Question, error)
+	// ListDueQuestionIDs retrieves the IDs of the undecided questions whose
+	// close time, or their event's, is not after now
+	ListDueQuestionIDs(ctx context.Context, now time.Time) ([]string, error)
+
+	// Option operations
+	AddOption(ctx context.Context, questionID string, option models.Option) error
+	GetOption(ctx context.Context, optionID string) (*models.Option, error)
+	UpdateOption(ctx context.Context, option models.Option) error
+	DeleteOption(ctx context.Context, optionID string) error
+	GetOptionsByQuestionID(ctx context.Context, questionID string) ([]models.Option, error)
+
+	// Participant operations
+	SaveParticipant(ctx context.Context, participant models.Participant) error
+	GetParticipant(ctx context.Context, participantID string) (*models.Participant, error)
+
+	// Ballot operations
+	GetBallotsByQuestionID(ctx context.Context, questionID string) ([]models.Ballot, error)
+	// ReplaceBallots atomically swaps all of a participant's ballots on a
+	// question for the given ones. An empty slice withdraws every vote.
+	ReplaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error
+
+	// Veto operations
+	// ReplaceVetoes atomically swaps all of a participant's vetoes on a
+	// question for the given ones. An empty slice withdraws every veto.
+	ReplaceVetoes(ctx context.Context, questionID string, participantID string, vetoes []models.Veto) error
+}
+
+// ErrConflict is returned for an update based on an older version of an item
+// than the one stored, and for ballots or vetoes that kept being changed by
+// the same participant at the same time
+var ErrConflict = errors.New("changed by someone else")
+
+// ErrInvalidCursor is returned for a page cursor that the store did not hand out
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// DeleteResumer is implemented by stores whose cascading deletes can be cut
+// short part way through. ResumeDeletes finishes any such deletes.
+type DeleteResumer interface {
+	ResumeDeletes(ctx context.Context) error
+}
+
+// GetStorageBackend returns the storage backend based on environment variables or defaults
+func GetStorageBackend() string {
+	if backend := os.Getenv("STORAGE_BACKEND"); backend != "" {
+		return backend
+	}
+	return DefaultBackend
+}
+
+// NewStore creates the Store selected by the STORAGE_BACKEND environment
+// variable, giving each of its operations the deadline set by STORE_TIMEOUT
+// and the variables next to it, see GetStoreTimeouts
+func NewStore() (Store, error) {
+	store, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	return WithTimeout(store, GetStoreTimeouts()), nil
+}
+
+// newBackend creates the storage backend selected by STORAGE_BACKEND
+func newBackend() (Store, error) {
+	switch backend := GetStorageBackend(); backend {
+	case BackendDynamoDB:
+		return NewDynamoStore()
+	case BackendMemory:
+		log.Printf("Using in-memory store, data will be lost on restart")
+		return NewMemoryStore(), nil
+	case BackendSQLite:
+		return NewSQLiteStore(GetSQLitePath())
+	case BackendPostgres:
+		return NewPostgresStore(GetDatabaseURL())
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", backend)
+	}
+}
+
+var (
+	_ Store = (*DynamoStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*SQLStore)(nil)
+
+	_ DeleteResumer = (*DynamoStore)(nil)
+	_ DeleteResumer = (*timeoutStore)(nil)
+)
+
+// createdAt returns when an item was created, now if it doesn't say
+func createdAt(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t.UTC()
+}
+
+// encodeCursor turns a store's position in a listing into an opaque cursor
+func encodeCursor(position any) string {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor made by encodeCursor back into position
+func decodeCursor(cursor string, position any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// idCursor is where a page of a listing held in memory ended: the ID of its
+// last item, and the position after it in case that item has since been
+// deleted
+type idCursor struct {
+	After    string `json:"after"`
+	Position int    `json:"position"`
+}
+
+// PageOf picks a page of at most limit items out of a listing held in memory,
+// given the IDs of its items in order, starting after the page that returned
+// cursor. It returns the bounds of the page and the cursor of the next one,
+// empty on the last page.
+func PageOf(ids []string, limit int, cursor string) (int, int, string, error) {
+	start := 0
+	if cursor != "" {
+		var after idCursor
+		if err := decodeCursor(cursor, &after); err != nil {
+			return 0, 0, "", err
+		}
+		start = min(max(after.Position, 0), len(ids))
+		for i, id := range ids {
+			if id == after.After {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := min(start+limit, len(ids))
+
+	var next string
+	if end < len(ids) {
+		next = encodeCursor(idCursor{After: ids[end-1], Position: end})
+	}
+	return start, end, next, nil
+}
+
+// checkBallots makes sure every ballot is for the given question and participant
+func checkBallots(questionID string, participantID string, ballots []models.Ballot) error {
+	for _, ballot := range ballots {
+		if ballot.QuestionID != questionID || ballot.ParticipantID != participantID {
+			return fmt.Errorf("ballot for option %s does not belong to participant on question %s", ballot.OptionID, questionID)
+		}
+	}
+	return nil
+}
+
+// checkVetoes makes sure every veto is for the given question and participant
+func checkVetoes(questionID string, participantID string, vetoes []models.Veto) error {
+	for _, veto := range vetoes {
+		if veto.QuestionID != questionID || veto.ParticipantID != participantID {
+			return fmt.Errorf("veto of option %s does not belong to participant on question %s", veto.OptionID, questionID)
+		}
+	}
+	return nil
+}
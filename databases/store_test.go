@@ -0,0 +1,53 @@
+package databases_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/databases/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) databases.Store {
+		return databases.NewMemoryStore()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) databases.Store {
+		store, err := databases.NewSQLiteStore(filepath.Join(t.TempDir(), "planzoco.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+// TestPostgresStore runs against the database at TEST_DATABASE_URL, which it
+// migrates and adds events to, and is skipped if that is not set
+func TestPostgresStore(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	storetest.Run(t, func(t *testing.T) databases.Store {
+		store, err := databases.NewPostgresStore(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+// TestDynamoStore runs against the DynamoDB Local at DYNAMODB_ENDPOINT, and is
+// skipped if that is not set
+func TestDynamoStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) databases.Store {
+		return databases.NewDynamoTestStore(t)
+	})
+}
@@ -0,0 +1,912 @@
+// Package storetest is the behavioural test suite every databases.Store
+// backend must pass, so that the handlers see the same thing whichever
+// backend keeps their data.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+)
+
+// Run runs the suite against the stores returned by newStore, which is called
+// once for each test. A store may be shared between tests and hold other
+// data, as every test makes events of its own.
+func Run(t *testing.T, newStore func(t *testing.T) databases.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store databases.Store)
+	}{
+		{"EventVersions", testEventVersions},
+		{"SetAdminToken", testSetAdminToken},
+		{"QuestionVersions", testQuestionVersions},
+		{"QuestionFixedFields", testQuestionFixedFields},
+		{"OptionVersions", testOptionVersions},
+		{"OptionOrder", testOptionOrder},
+		{"VoteCounts", testVoteCounts},
+		{"AvailabilityCounts", testAvailabilityCounts},
+		{"ManySlots", testManySlots},
+		{"ConcurrentBallots", testConcurrentBallots},
+		{"CloseQuestion", testCloseQuestion},
+		{"DueQuestions", testDueQuestions},
+		{"DeleteEvent", testDeleteEvent},
+		{"DeleteQuestion", testDeleteQuestion},
+		{"DeleteOption", testDeleteOption},
+		{"ListEventsPage", testListEventsPage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newStore(t))
+		})
+	}
+}
+
+func testEventVersions(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+
+	stored := getEvent(t, store, event.ID)
+	if stored.Version != 0 {
+		t.Fatalf("new event has version %d, want 0", stored.Version)
+	}
+
+	stored.Name = "Renamed"
+	if err := store.UpdateEvent(ctx, *stored); err != nil {
+		t.Fatalf("failed to update event: %v", err)
+	}
+	updated := getEvent(t, store, event.ID)
+	if updated.Name != "Renamed" || updated.Version != 1 {
+		t.Errorf("updated event is %q at version %d, want %q at version 1", updated.Name, updated.Version, "Renamed")
+	}
+	if updated.AdminToken != event.AdminToken {
+		t.Errorf("update changed the admin token")
+	}
+
+	// stored is still at version 0
+	stored.Name = "Stale"
+	if err := store.UpdateEvent(ctx, *stored); !errors.Is(err, databases.ErrConflict) {
+		t.Errorf("stale update returned %v, want ErrConflict", err)
+	}
+	if name := getEvent(t, store, event.ID).Name; name != "Renamed" {
+		t.Errorf("stale update renamed the event to %q", name)
+	}
+}
+
+func testSetAdminToken(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	legacy := models.NewEvent(newID(t), "Before admin tokens")
+	if err := store.CreateEvent(ctx, legacy); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	token := newID(t)
+	set, err := store.SetAdminToken(ctx, legacy.ID, token)
+	if err != nil || !set {
+		t.Fatalf("SetAdminToken = %v, %v, want true", set, err)
+	}
+	if stored := getEvent(t, store, legacy.ID); stored.AdminToken != token {
+		t.Errorf("event has admin token %q, want %q", stored.AdminToken, token)
+	}
+
+	set, err = store.SetAdminToken(ctx, legacy.ID, newID(t))
+	if err != nil || set {
+		t.Errorf("setting again = %v, %v, want false", set, err)
+	}
+	if stored := getEvent(t, store, legacy.ID); stored.AdminToken != token {
+		t.Errorf("setting again replaced the admin token")
+	}
+
+	event := createEvent(t, store)
+	set, err = store.SetAdminToken(ctx, event.ID, newID(t))
+	if err != nil || set {
+		t.Errorf("SetAdminToken on an event with a token = %v, %v, want false", set, err)
+	}
+	if stored := getEvent(t, store, event.ID); stored.AdminToken != event.AdminToken {
+		t.Errorf("SetAdminToken replaced the admin token of an event that had one")
+	}
+
+	if _, err := store.SetAdminToken(ctx, newID(t), newID(t)); err == nil {
+		t.Errorf("SetAdminToken on a missing event succeeded")
+	}
+}
+
+func testQuestionVersions(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+
+	stored := getQuestion(t, store, question.ID)
+	stored.Text = "Changed"
+	if err := store.UpdateQuestion(ctx, *stored); err != nil {
+		t.Fatalf("failed to update question: %v", err)
+	}
+	updated := getQuestion(t, store, question.ID)
+	if updated.Text != "Changed" || updated.Version != stored.Version+1 {
+		t.Errorf("updated question is %q at version %d, want %q at version %d", updated.Text, updated.Version, "Changed", stored.Version+1)
+	}
+
+	stored.Text = "Stale"
+	if err := store.UpdateQuestion(ctx, *stored); !errors.Is(err, databases.ErrConflict) {
+		t.Errorf("stale update returned %v, want ErrConflict", err)
+	}
+
+	// Closing the question is a change too
+	closed, err := store.CloseQuestion(ctx, question.ID, models.Decision{DecidedAt: time.Now().UTC()})
+	if err != nil || !closed {
+		t.Fatalf("failed to close question: %v", err)
+	}
+	updated.Text = "After closing"
+	if err := store.UpdateQuestion(ctx, *updated); !errors.Is(err, databases.ErrConflict) {
+		t.Errorf("update from before closing returned %v, want ErrConflict", err)
+	}
+}
+
+func testQuestionFixedFields(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	other := createEvent(t, store)
+
+	question := models.NewQuestion(newID(t), event.ID, "How good?")
+	question.Type = models.ScoreQuestion
+	question.ScaleMin, question.ScaleMax = 1, 5
+	if err := store.AddQuestion(ctx, event.ID, question); err != nil {
+		t.Fatalf("failed to add question: %v", err)
+	}
+
+	// An update made from a stored copy, keys and all, may only change what
+	// an update can
+	stored := getQuestion(t, store, question.ID)
+	stored.Text = "How much?"
+	stored.Type = models.PluralityQuestion
+	stored.ScaleMin, stored.ScaleMax = 0, 100
+	stored.EventID = other.ID
+	if err := store.UpdateQuestion(ctx, *stored); err != nil {
+		t.Fatalf("failed to update question: %v", err)
+	}
+
+	updated := getQuestion(t, store, question.ID)
+	if updated.Text != "How much?" {
+		t.Errorf("updated question has text %q, want %q", updated.Text, "How much?")
+	}
+	if updated.Type != models.ScoreQuestion {
+		t.Errorf("update changed the type to %q", updated.Type)
+	}
+	if updated.ScaleMin != 1 || updated.ScaleMax != 5 {
+		t.Errorf("update changed the scale to %d-%d", updated.ScaleMin, updated.ScaleMax)
+	}
+	if updated.EventID != event.ID {
+		t.Errorf("update moved the question to event %s", updated.EventID)
+	}
+	if questions := getEvent(t, store, other.ID).Questions; len(questions) != 0 {
+		t.Errorf("update added the question to another event: %+v", questions)
+	}
+	if questions := getEvent(t, store, event.ID).Questions; len(questions) != 1 {
+		t.Errorf("event has %d questions after the update, want 1", len(questions))
+	}
+}
+
+func testOptionVersions(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	option := addOption(t, store, question.ID, "Thai", time.Now().UTC())
+
+	stored := getOption(t, store, option.ID)
+	stored.Text = "Thai food"
+	if err := store.UpdateOption(ctx, *stored); err != nil {
+		t.Fatalf("failed to update option: %v", err)
+	}
+	updated := getOption(t, store, option.ID)
+	if updated.Text != "Thai food" || updated.Version != stored.Version+1 {
+		t.Errorf("updated option is %q at version %d, want %q at version %d", updated.Text, updated.Version, "Thai food", stored.Version+1)
+	}
+
+	stored.Text = "Stale"
+	if err := store.UpdateOption(ctx, *stored); !errors.Is(err, databases.ErrConflict) {
+		t.Errorf("stale update returned %v, want ErrConflict", err)
+	}
+}
+
+func testOptionOrder(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+
+	// Added in the reverse order of their IDs, so sorting by ID gets it wrong
+	ids := []string{newID(t), newID(t), newID(t)}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	for i, id := range ids {
+		option := models.NewOption(id, question.ID, "Option "+id)
+		option.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		if err := store.AddOption(ctx, question.ID, option); err != nil {
+			t.Fatalf("failed to add option: %v", err)
+		}
+	}
+
+	options, err := store.GetOptionsByQuestionID(ctx, question.ID)
+	if err != nil {
+		t.Fatalf("failed to get options: %v", err)
+	}
+	checkOrder(t, "GetOptionsByQuestionID", options, ids)
+	checkOrder(t, "GetQuestion", getQuestion(t, store, question.ID).Options, ids)
+
+	questions := getEvent(t, store, event.ID).Questions
+	if len(questions) != 1 {
+		t.Fatalf("event has %d questions, want 1", len(questions))
+	}
+	checkOrder(t, "GetEvent", questions[0].Options, ids)
+}
+
+func testVoteCounts(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	a := addOption(t, store, question.ID, "A", time.Now().UTC())
+	b := addOption(t, store, question.ID, "B", time.Now().UTC().Add(time.Second))
+
+	p1, p2, p3 := newID(t), newID(t), newID(t)
+	vote(t, store, question.ID, p1, a.ID)
+	vote(t, store, question.ID, p2, a.ID)
+	vote(t, store, question.ID, p3, b.ID)
+	checkVotes(t, store, question.ID, map[string]int{a.ID: 2, b.ID: 1})
+
+	// Changing a vote moves it
+	vote(t, store, question.ID, p2, b.ID)
+	checkVotes(t, store, question.ID, map[string]int{a.ID: 1, b.ID: 2})
+
+	// An empty ballot withdraws it
+	vote(t, store, question.ID, p1)
+	checkVotes(t, store, question.ID, map[string]int{a.ID: 0, b.ID: 2})
+
+	ballots, err := store.GetBallotsByQuestionID(ctx, question.ID)
+	if err != nil {
+		t.Fatalf("failed to get ballots: %v", err)
+	}
+	if len(ballots) != 2 {
+		t.Errorf("got %d ballots, want 2", len(ballots))
+	}
+
+	// Vetoes are counted apart from votes
+	veto := models.NewVeto(question.ID, models.NewParticipant(p3, ""), a.ID)
+	if err := store.ReplaceVetoes(ctx, question.ID, p3, []models.Veto{veto}); err != nil {
+		t.Fatalf("failed to veto: %v", err)
+	}
+	if option := getOption(t, store, a.ID); option.Vetoes != 1 || option.Votes != 0 {
+		t.Errorf("vetoed option has %d votes and %d vetoes, want 0 and 1", option.Votes, option.Vetoes)
+	}
+	if option := getQuestion(t, store, question.ID).Options[0]; option.Vetoes != 1 {
+		t.Errorf("vetoed option has %d vetoes on its question, want 1", option.Vetoes)
+	}
+
+	// Options of other questions can't be voted for
+	other := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	elsewhere := addOption(t, store, other.ID, "Elsewhere", time.Now().UTC())
+	ballot := models.NewBallot(question.ID, models.NewParticipant(p1, ""), elsewhere.ID)
+	if err := store.ReplaceBallots(ctx, question.ID, p1, []models.Ballot{ballot}); err == nil {
+		t.Errorf("voted for an option of another question")
+	}
+	checkVotes(t, store, question.ID, map[string]int{a.ID: 0, b.ID: 2})
+}
+
+func testAvailabilityCounts(t *testing.T, store databases.Store) {
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.AvailabilityQuestion)
+	slot := addOption(t, store, question.ID, "Friday", time.Now().UTC())
+
+	answers := []models.Availability{models.AvailableYes, models.AvailableMaybe, models.AvailableNo}
+	for _, answer := range answers {
+		participant := models.NewParticipant(newID(t), "")
+		saveParticipant(t, store, participant)
+
+		ballot := models.NewBallot(question.ID, participant, slot.ID)
+		ballot.Availability = answer
+		if err := store.ReplaceBallots(context.Background(), question.ID, participant.ID, []models.Ballot{ballot}); err != nil {
+			t.Fatalf("failed to mark availability: %v", err)
+		}
+	}
+
+	// A "no" is not a vote
+	checkVotes(t, store, question.ID, map[string]int{slot.ID: 2})
+}
+
+// manySlots is how many slots the availability question of testManySlots
+// has, more than half of what a DynamoDB transaction holds
+const manySlots = 60
+
+func testManySlots(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.AvailabilityQuestion)
+	other := addQuestion(t, store, event.ID, models.AvailabilityQuestion)
+	elsewhere := addOption(t, store, other.ID, "Elsewhere", time.Now().UTC())
+
+	start := time.Now().UTC()
+	var slots []models.Option
+	for i := 0; i < manySlots; i++ {
+		slots = append(slots, addOption(t, store, question.ID, fmt.Sprintf("Slot %d", i), start.Add(time.Duration(i)*time.Millisecond)))
+	}
+
+	participant := models.NewParticipant(newID(t), "")
+	saveParticipant(t, store, participant)
+	answer := func(available func(i int) models.Availability) []models.Ballot {
+		var ballots []models.Ballot
+		for i, slot := range slots {
+			ballot := models.NewBallot(question.ID, participant, slot.ID)
+			ballot.Availability = available(i)
+			ballots = append(ballots, ballot)
+		}
+		return ballots
+	}
+
+	yes := answer(func(int) models.Availability { return models.AvailableYes })
+	if err := store.ReplaceBallots(ctx, question.ID, participant.ID, yes); err != nil {
+		t.Fatalf("failed to answer %d slots: %v", manySlots, err)
+	}
+	want := make(map[string]int)
+	for _, slot := range slots {
+		want[slot.ID] = 1
+	}
+	checkVotes(t, store, question.ID, want)
+
+	// Changing every answer replaces every ballot
+	alternating := answer(func(i int) models.Availability {
+		if i%2 == 0 {
+			return models.AvailableNo
+		}
+		return models.AvailableMaybe
+	})
+	if err := store.ReplaceBallots(ctx, question.ID, participant.ID, alternating); err != nil {
+		t.Fatalf("failed to change %d answers: %v", manySlots, err)
+	}
+	for i, slot := range slots {
+		want[slot.ID] = i % 2
+	}
+	checkVotes(t, store, question.ID, want)
+
+	// A ballot on another question's option is refused, and nothing changes
+	stray := models.NewBallot(question.ID, participant, elsewhere.ID)
+	stray.Availability = models.AvailableYes
+	if err := store.ReplaceBallots(ctx, question.ID, participant.ID, append(yes, stray)); err == nil {
+		t.Errorf("ReplaceBallots accepted a ballot on another question's option")
+	}
+	checkVotes(t, store, question.ID, want)
+}
+
+// concurrentVoters is how many participants vote at once in
+// testConcurrentBallots
+const concurrentVoters = 300
+
+func testConcurrentBallots(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	a := addOption(t, store, question.ID, "A", time.Now().UTC())
+	b := addOption(t, store, question.ID, "B", time.Now().UTC().Add(time.Second))
+
+	participants := make([]models.Participant, concurrentVoters)
+	for i := range participants {
+		participants[i] = models.NewParticipant(newID(t), "")
+		saveParticipant(t, store, participants[i])
+	}
+
+	// Every participant votes at once, every other one for A
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentVoters)
+	for i, participant := range participants {
+		optionID := a.ID
+		if i%2 == 1 {
+			optionID = b.ID
+		}
+		wg.Add(1)
+		go func(participant models.Participant, optionID string) {
+			defer wg.Done()
+			ballot := models.NewBallot(question.ID, participant, optionID)
+			errs <- store.ReplaceBallots(ctx, question.ID, participant.ID, []models.Ballot{ballot})
+		}(participant, optionID)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to vote: %v", err)
+		}
+	}
+	checkVotes(t, store, question.ID, map[string]int{a.ID: concurrentVoters / 2, b.ID: concurrentVoters / 2})
+
+	// One participant changes their vote many times at once. Each change
+	// either wins or gives up with ErrConflict, but only one vote is left.
+	participant := participants[0]
+	errs = make(chan error, concurrentVoters)
+	for i := 0; i < concurrentVoters; i++ {
+		optionID := a.ID
+		if i%2 == 1 {
+			optionID = b.ID
+		}
+		wg.Add(1)
+		go func(optionID string) {
+			defer wg.Done()
+			ballot := models.NewBallot(question.ID, participant, optionID)
+			errs <- store.ReplaceBallots(ctx, question.ID, participant.ID, []models.Ballot{ballot})
+		}(optionID)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil && !errors.Is(err, databases.ErrConflict) {
+			t.Errorf("racing vote returned %v, want nil or ErrConflict", err)
+		}
+	}
+
+	ballots, err := store.GetBallotsByQuestionID(ctx, question.ID)
+	if err != nil {
+		t.Fatalf("failed to get ballots: %v", err)
+	}
+	if len(ballots) != concurrentVoters {
+		t.Errorf("got %d ballots, want %d", len(ballots), concurrentVoters)
+	}
+	var own []models.Ballot
+	for _, ballot := range ballots {
+		if ballot.ParticipantID == participant.ID {
+			own = append(own, ballot)
+		}
+	}
+	if len(own) != 1 {
+		t.Fatalf("racing participant has %d ballots, want 1", len(own))
+	}
+
+	// The participant's first vote was for A, and was moved or stayed
+	want := map[string]int{a.ID: concurrentVoters/2 - 1, b.ID: concurrentVoters / 2}
+	want[own[0].OptionID]++
+	checkVotes(t, store, question.ID, want)
+}
+
+func testCloseQuestion(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	option := addOption(t, store, question.ID, "A", time.Now().UTC())
+
+	first := models.Decision{Winners: []models.Option{*getOption(t, store, option.ID)}, DecidedAt: time.Now().UTC().Truncate(time.Second)}
+	closed, err := store.CloseQuestion(ctx, question.ID, first)
+	if err != nil || !closed {
+		t.Fatalf("CloseQuestion = %v, %v, want true", closed, err)
+	}
+
+	closed, err = store.CloseQuestion(ctx, question.ID, models.Decision{DecidedAt: time.Now().UTC()})
+	if err != nil || closed {
+		t.Fatalf("closing again = %v, %v, want false", closed, err)
+	}
+	decision := getQuestion(t, store, question.ID).Decision
+	if decision == nil || len(decision.Winners) != 1 || decision.Winners[0].ID != option.ID {
+		t.Errorf("closing again replaced the decision: %+v", decision)
+	}
+
+	if err := store.UpdateDecision(ctx, question.ID, models.Decision{DecidedAt: first.DecidedAt}); err != nil {
+		t.Fatalf("failed to update decision: %v", err)
+	}
+	if decision := getQuestion(t, store, question.ID).Decision; decision == nil || len(decision.Winners) != 0 {
+		t.Errorf("decision not updated: %+v", decision)
+	}
+}
+
+func testDueQuestions(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	// closingQuestion adds a question that closes at the given time, if any
+	closingQuestion := func(eventID string, closesAt *time.Time) string {
+		question := models.NewQuestion(newID(t), eventID, "When?")
+		question.ClosesAt = closesAt
+		if err := store.AddQuestion(ctx, eventID, question); err != nil {
+			t.Fatalf("failed to add question: %v", err)
+		}
+		return question.ID
+	}
+	// closingEvent creates an event that closes at the given time, if any
+	closingEvent := func(closesAt *time.Time) string {
+		event := models.NewEvent(newID(t), "Dinner")
+		event.AdminToken = newID(t)
+		event.ClosesAt = closesAt
+		if err := store.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("failed to create event: %v", err)
+		}
+		return event.ID
+	}
+
+	open := closingEvent(nil)
+	closed := closingEvent(&past)
+	closing := closingEvent(&future)
+
+	want := map[string]bool{
+		closingQuestion(open, &past):   true,
+		closingQuestion(open, &future): false,
+		closingQuestion(open, nil):     false,
+		closingQuestion(closed, nil):   true,
+		// The earlier close time counts
+		closingQuestion(closed, &future): true,
+		closingQuestion(closing, &past):  true,
+		closingQuestion(closing, nil):    false,
+	}
+
+	// Decided questions are done with, however long ago they closed
+	decided := closingQuestion(closed, &past)
+	if _, err := store.CloseQuestion(ctx, decided, models.Decision{DecidedAt: now}); err != nil {
+		t.Fatalf("failed to close question: %v", err)
+	}
+	want[decided] = false
+
+	due, err := store.ListDueQuestionIDs(ctx, now)
+	if err != nil {
+		t.Fatalf("failed to list due questions: %v", err)
+	}
+	listed := make(map[string]int)
+	for _, questionID := range due {
+		listed[questionID]++
+	}
+	for questionID, isDue := range want {
+		switch {
+		case isDue && listed[questionID] != 1:
+			t.Errorf("due question %s listed %d times, want once", questionID, listed[questionID])
+		case !isDue && listed[questionID] != 0:
+			t.Errorf("question %s listed as due, but it is not", questionID)
+		}
+	}
+}
+
+func testDeleteEvent(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	kept := createEvent(t, store)
+	keptQuestion := addQuestion(t, store, kept.ID, models.PluralityQuestion)
+	keptOption := addOption(t, store, keptQuestion.ID, "Kept", time.Now().UTC())
+	vote(t, store, keptQuestion.ID, newID(t), keptOption.ID)
+
+	var questions []models.Question
+	var options []models.Option
+	for i := 0; i < 2; i++ {
+		question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+		questions = append(questions, question)
+		for j := 0; j < 2; j++ {
+			option := addOption(t, store, question.ID, "Option", time.Now().UTC().Add(time.Duration(j)*time.Second))
+			options = append(options, option)
+			participant := newID(t)
+			vote(t, store, question.ID, participant, option.ID)
+			veto := models.NewVeto(question.ID, models.NewParticipant(participant, ""), option.ID)
+			if err := store.ReplaceVetoes(ctx, question.ID, participant, []models.Veto{veto}); err != nil {
+				t.Fatalf("failed to veto: %v", err)
+			}
+		}
+	}
+
+	if err := store.DeleteEvent(ctx, event.ID); err != nil {
+		t.Fatalf("failed to delete event: %v", err)
+	}
+
+	if stored, err := store.GetEvent(ctx, event.ID); err != nil || stored != nil {
+		t.Errorf("GetEvent after deleting = %v, %v, want nil", stored, err)
+	}
+	for _, question := range questions {
+		checkQuestionGone(t, store, question.ID)
+	}
+	for _, option := range options {
+		if stored, err := store.GetOption(ctx, option.ID); err != nil || stored != nil {
+			t.Errorf("GetOption after deleting its event = %v, %v, want nil", stored, err)
+		}
+	}
+	events, err := store.ListEvents(ctx)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	for _, listed := range events {
+		if listed.ID == event.ID {
+			t.Errorf("deleted event still listed")
+		}
+	}
+
+	// Everything else stays
+	checkVotes(t, store, keptQuestion.ID, map[string]int{keptOption.ID: 1})
+
+	// Deleting it again does no harm
+	if err := store.DeleteEvent(ctx, event.ID); err != nil {
+		t.Errorf("deleting a deleted event failed: %v", err)
+	}
+}
+
+func testDeleteQuestion(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	deleted := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	kept := addQuestion(t, store, event.ID, models.PluralityQuestion)
+
+	deletedOption := addOption(t, store, deleted.ID, "Deleted", time.Now().UTC())
+	keptOption := addOption(t, store, kept.ID, "Kept", time.Now().UTC())
+	participant := newID(t)
+	vote(t, store, deleted.ID, participant, deletedOption.ID)
+	vote(t, store, kept.ID, participant, keptOption.ID)
+
+	if err := store.DeleteQuestion(ctx, deleted.ID); err != nil {
+		t.Fatalf("failed to delete question: %v", err)
+	}
+
+	checkQuestionGone(t, store, deleted.ID)
+	if stored, err := store.GetOption(ctx, deletedOption.ID); err != nil || stored != nil {
+		t.Errorf("GetOption after deleting its question = %v, %v, want nil", stored, err)
+	}
+
+	questions := getEvent(t, store, event.ID).Questions
+	if len(questions) != 1 || questions[0].ID != kept.ID {
+		t.Fatalf("event has questions %v, want only %s", questionIDs(questions), kept.ID)
+	}
+	checkVotes(t, store, kept.ID, map[string]int{keptOption.ID: 1})
+}
+
+func testDeleteOption(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+	event := createEvent(t, store)
+	question := addQuestion(t, store, event.ID, models.PluralityQuestion)
+	deleted := addOption(t, store, question.ID, "Deleted", time.Now().UTC())
+	kept := addOption(t, store, question.ID, "Kept", time.Now().UTC().Add(time.Second))
+
+	vote(t, store, question.ID, newID(t), deleted.ID)
+	vote(t, store, question.ID, newID(t), kept.ID)
+
+	if err := store.DeleteOption(ctx, deleted.ID); err != nil {
+		t.Fatalf("failed to delete option: %v", err)
+	}
+
+	if stored, err := store.GetOption(ctx, deleted.ID); err != nil || stored != nil {
+		t.Errorf("GetOption after deleting = %v, %v, want nil", stored, err)
+	}
+	ballots, err := store.GetBallotsByQuestionID(ctx, question.ID)
+	if err != nil {
+		t.Fatalf("failed to get ballots: %v", err)
+	}
+	if len(ballots) != 1 || ballots[0].OptionID != kept.ID {
+		t.Errorf("ballots after deleting an option = %+v, want one for %s", ballots, kept.ID)
+	}
+	checkVotes(t, store, question.ID, map[string]int{kept.ID: 1})
+}
+
+func testListEventsPage(t *testing.T, store databases.Store) {
+	ctx := context.Background()
+
+	created := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		event := createEvent(t, store)
+		addQuestion(t, store, event.ID, models.PluralityQuestion)
+		created[event.ID] = true
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 1000 {
+			t.Fatalf("paging never ended")
+		}
+		events, next, err := store.ListEventsPage(ctx, 2, cursor)
+		if err != nil {
+			t.Fatalf("failed to list events: %v", err)
+		}
+		if len(events) > 2 {
+			t.Fatalf("page has %d events, want at most 2", len(events))
+		}
+		for _, event := range events {
+			seen[event.ID]++
+			if len(event.Questions) != 0 {
+				t.Errorf("event %s listed with its questions", event.ID)
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	for id := range created {
+		if seen[id] != 1 {
+			t.Errorf("event %s listed %d times, want once", id, seen[id])
+		}
+	}
+
+	if _, _, err := store.ListEventsPage(ctx, 2, "not a cursor!"); !errors.Is(err, databases.ErrInvalidCursor) {
+		t.Errorf("listing with a made up cursor returned %v, want ErrInvalidCursor", err)
+	}
+}
+
+// newID returns a new random ID
+func newID(t *testing.T) string {
+	t.Helper()
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func createEvent(t *testing.T, store databases.Store) models.Event {
+	t.Helper()
+
+	event := models.NewEvent(newID(t), "Dinner")
+	event.AdminToken = newID(t)
+	if err := store.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	return event
+}
+
+func addQuestion(t *testing.T, store databases.Store, eventID string, questionType models.QuestionType) models.Question {
+	t.Helper()
+
+	question := models.NewQuestion(newID(t), eventID, "Where?")
+	question.Type = questionType
+	if err := store.AddQuestion(context.Background(), eventID, question); err != nil {
+		t.Fatalf("failed to add question: %v", err)
+	}
+	return question
+}
+
+func addOption(t *testing.T, store databases.Store, questionID string, text string, createdAt time.Time) models.Option {
+	t.Helper()
+
+	option := models.NewOption(newID(t), questionID, text)
+	option.CreatedAt = createdAt
+	if err := store.AddOption(context.Background(), questionID, option); err != nil {
+		t.Fatalf("failed to add option: %v", err)
+	}
+	return option
+}
+
+func saveParticipant(t *testing.T, store databases.Store, participant models.Participant) {
+	t.Helper()
+
+	if err := store.SaveParticipant(context.Background(), participant); err != nil {
+		t.Fatalf("failed to save participant: %v", err)
+	}
+}
+
+// vote replaces the participant's ballots on the question with one for each
+// of the options, saving the participant first the way the handlers do
+func vote(t *testing.T, store databases.Store, questionID string, participantID string, optionIDs ...string) {
+	t.Helper()
+
+	participant := models.NewParticipant(participantID, "")
+	saveParticipant(t, store, participant)
+
+	ballots := make([]models.Ballot, 0, len(optionIDs))
+	for _, optionID := range optionIDs {
+		ballots = append(ballots, models.NewBallot(questionID, participant, optionID))
+	}
+	if err := store.ReplaceBallots(context.Background(), questionID, participantID, ballots); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+}
+
+func getEvent(t *testing.T, store databases.Store, eventID string) *models.Event {
+	t.Helper()
+
+	event, err := store.GetEvent(context.Background(), eventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent(%s) = %v, %v", eventID, event, err)
+	}
+	return event
+}
+
+func getQuestion(t *testing.T, store databases.Store, questionID string) *models.Question {
+	t.Helper()
+
+	question, err := store.GetQuestion(context.Background(), questionID)
+	if err != nil || question == nil {
+		t.Fatalf("GetQuestion(%s) = %v, %v", questionID, question, err)
+	}
+	return question
+}
+
+func getOption(t *testing.T, store databases.Store, optionID string) *models.Option {
+	t.Helper()
+
+	option, err := store.GetOption(context.Background(), optionID)
+	if err != nil || option == nil {
+		t.Fatalf("GetOption(%s) = %v, %v", optionID, option, err)
+	}
+	return option
+}
+
+// checkVotes checks the votes of a question's options, as every read that
+// returns them counts them
+func checkVotes(t *testing.T, store databases.Store, questionID string, want map[string]int) {
+	t.Helper()
+
+	options, err := store.GetOptionsByQuestionID(context.Background(), questionID)
+	if err != nil {
+		t.Fatalf("failed to get options: %v", err)
+	}
+	question := getQuestion(t, store, questionID)
+	event := getEvent(t, store, question.EventID)
+
+	var fromEvent []models.Option
+	for _, q := range event.Questions {
+		if q.ID == questionID {
+			fromEvent = q.Options
+		}
+	}
+
+	for _, read := range []struct {
+		name    string
+		options []models.Option
+	}{
+		{"GetOptionsByQuestionID", options},
+		{"GetQuestion", question.Options},
+		{"GetEvent", fromEvent},
+	} {
+		if len(read.options) != len(want) {
+			t.Errorf("%s returned %d options, want %d", read.name, len(read.options), len(want))
+		}
+		for _, option := range read.options {
+			if option.Votes != want[option.ID] {
+				t.Errorf("%s counts %d votes for option %s, want %d", read.name, option.Votes, option.ID, want[option.ID])
+			}
+		}
+	}
+
+	for optionID, votes := range want {
+		if option := getOption(t, store, optionID); option.Votes != votes {
+			t.Errorf("GetOption counts %d votes for option %s, want %d", option.Votes, optionID, votes)
+		}
+	}
+}
+
+// checkQuestionGone checks that nothing of a deleted question can be read
+func checkQuestionGone(t *testing.T, store databases.Store, questionID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if question, err := store.GetQuestion(ctx, questionID); err != nil || question != nil {
+		t.Errorf("GetQuestion after deleting = %v, %v, want nil", question, err)
+	}
+	if options, err := store.GetOptionsByQuestionID(ctx, questionID); err != nil || len(options) != 0 {
+		t.Errorf("GetOptionsByQuestionID after deleting = %v, %v, want none", options, err)
+	}
+	if ballots, err := store.GetBallotsByQuestionID(ctx, questionID); err != nil || len(ballots) != 0 {
+		t.Errorf("GetBallotsByQuestionID after deleting = %v, %v, want none", ballots, err)
+	}
+}
+
+// checkOrder checks that the options are the ones with the given IDs, in order
+func checkOrder(t *testing.T, read string, options []models.Option, ids []string) {
+	t.Helper()
+
+	got := make([]string, 0, len(options))
+	for _, option := range options {
+		got = append(got, option.ID)
+	}
+	if len(got) != len(ids) {
+		t.Errorf("%s returned options %v, want %v", read, got, ids)
+		return
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Errorf("%s returned options %v, want %v", read, got, ids)
+			return
+		}
+	}
+}
+
+func questionIDs(questions []models.Question) []string {
+	ids := make([]string, 0, len(questions))
+	for _, question := range questions {
+		ids = append(ids, question.ID)
+	}
+	return ids
+}
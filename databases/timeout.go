@@ -0,0 +1,257 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+)
+
+const (
+	// DefaultStoreTimeout is how long a single read or write of the store may
+	// take if the environment doesn't say otherwise
+	DefaultStoreTimeout = 5 * time.Second
+	// DefaultDeleteTimeout is how long a delete may take if STORE_DELETE_TIMEOUT
+	// doesn't say otherwise. Deleting an event takes everything in it along, in
+	// batches on DynamoDB, so it gets much longer than other writes.
+	DefaultDeleteTimeout = 2 * time.Minute
+)
+
+// StoreTimeouts are the deadlines of the operations of a Store, see WithTimeout
+type StoreTimeouts struct {
+	Read   time.Duration // Get and list operations
+	Write  time.Duration // Creates, updates, ballots and vetoes
+	Delete time.Duration // Deletes, which cascade to everything beneath what they delete
+}
+
+// GetStoreTimeouts returns how long store operations may take. Reads and
+// writes take STORE_READ_TIMEOUT and STORE_WRITE_TIMEOUT (eg "2s"), falling
+// back to STORE_TIMEOUT and then the default; deletes take
+// STORE_DELETE_TIMEOUT or their own, longer default.
+func GetStoreTimeouts() StoreTimeouts {
+	timeout := durationFromEnv("STORE_TIMEOUT", DefaultStoreTimeout)
+	return StoreTimeouts{
+		Read:   durationFromEnv("STORE_READ_TIMEOUT", timeout),
+		Write:  durationFromEnv("STORE_WRITE_TIMEOUT", timeout),
+		Delete: durationFromEnv("STORE_DELETE_TIMEOUT", DefaultDeleteTimeout),
+	}
+}
+
+// durationFromEnv reads a positive duration from the given environment
+// variable, or returns fallback if it is unset or invalid
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(name); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+		log.Printf("Ignoring invalid %s %q", name, value)
+	}
+	return fallback
+}
+
+// WithTimeout wraps a Store so that every operation gives up once it has
+// taken longer than the timeout for its kind, or as soon as its context is
+// done if that comes first. An operation that gives up returns an error that
+// wraps context.DeadlineExceeded or context.Canceled, whatever error the
+// backend itself returned.
+func WithTimeout(store Store, timeouts StoreTimeouts) Store {
+	return &timeoutStore{store: store, timeouts: timeouts}
+}
+
+// timeoutStore is a Store with a deadline on every operation, see WithTimeout
+type timeoutStore struct {
+	store    Store
+	timeouts StoreTimeouts
+}
+
+// ResumeDeletes finishes deletes that were cut short, if the wrapped store
+// can have any. It can take a while, so it is not given a deadline.
+func (s *timeoutStore) ResumeDeletes(ctx context.Context) error {
+	if resumer, ok := s.store.(DeleteResumer); ok {
+		return resumer.ResumeDeletes(ctx)
+	}
+	return nil
+}
+
+func (s *timeoutStore) CreateEvent(ctx context.Context, event models.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.CreateEvent(ctx, event))
+}
+
+func (s *timeoutStore) GetEvent(ctx context.Context, eventID string) (*models.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	event, err := s.store.GetEvent(ctx, eventID)
+	return event, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) UpdateEvent(ctx context.Context, event models.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.UpdateEvent(ctx, event))
+}
+
+func (s *timeoutStore) SetAdminToken(ctx context.Context, eventID string, token string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	set, err := s.store.SetAdminToken(ctx, eventID, token)
+	return set, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) DeleteEvent(ctx context.Context, eventID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+	return timedOut(ctx, s.store.DeleteEvent(ctx, eventID))
+}
+
+func (s *timeoutStore) ListEvents(ctx context.Context) ([]models.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	events, err := s.store.ListEvents(ctx)
+	return events, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) ListEventsPage(ctx context.Context, limit int, cursor string) ([]models.Event, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	events, next, err := s.store.ListEventsPage(ctx, limit, cursor)
+	return events, next, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) AddQuestion(ctx context.Context, eventID string, question models.Question) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.AddQuestion(ctx, eventID, question))
+}
+
+func (s *timeoutStore) GetQuestion(ctx context.Context, questionID string) (*models.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	question, err := s.store.GetQuestion(ctx, questionID)
+	return question, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) GetQuestionWithEvent(ctx context.Context, questionID string) (*models.Question, *models.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	question, event, err := s.store.GetQuestionWithEvent(ctx, questionID)
+	return question, event, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) UpdateQuestion(ctx context.Context, question models.Question) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.UpdateQuestion(ctx, question))
+}
+
+func (s *timeoutStore) CloseQuestion(ctx context.Context, questionID string, decision models.Decision) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	closed, err := s.store.CloseQuestion(ctx, questionID, decision)
+	return closed, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) UpdateDecision(ctx context.Context, questionID string, decision models.Decision) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.UpdateDecision(ctx, questionID, decision))
+}
+
+func (s *timeoutStore) DeleteQuestion(ctx context.Context, questionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+	return timedOut(ctx, s.store.DeleteQuestion(ctx, questionID))
+}
+
+func (s *timeoutStore) GetQuestionsByEventID(ctx context.Context, eventID string) ([]models.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	questions, err := s.store.GetQuestionsByEventID(ctx, eventID)
+	return questions, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) ListDueQuestionIDs(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	questionIDs, err := s.store.ListDueQuestionIDs(ctx, now)
+	return questionIDs, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) AddOption(ctx context.Context, questionID string, option models.Option) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.AddOption(ctx, questionID, option))
+}
+
+func (s *timeoutStore) GetOption(ctx context.Context, optionID string) (*models.Option, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	option, err := s.store.GetOption(ctx, optionID)
+	return option, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) UpdateOption(ctx context.Context, option models.Option) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.UpdateOption(ctx, option))
+}
+
+func (s *timeoutStore) DeleteOption(ctx context.Context, optionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+	return timedOut(ctx, s.store.DeleteOption(ctx, optionID))
+}
+
+func (s *timeoutStore) GetOptionsByQuestionID(ctx context.Context, questionID string) ([]models.Option, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	options, err := s.store.GetOptionsByQuestionID(ctx, questionID)
+	return options, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) SaveParticipant(ctx context.Context, participant models.Participant) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.SaveParticipant(ctx, participant))
+}
+
+func (s *timeoutStore) GetParticipant(ctx context.Context, participantID string) (*models.Participant, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	participant, err := s.store.GetParticipant(ctx, participantID)
+	return participant, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) GetBallotsByQuestionID(ctx context.Context, questionID string) ([]models.Ballot, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	ballots, err := s.store.GetBallotsByQuestionID(ctx, questionID)
+	return ballots, timedOut(ctx, err)
+}
+
+func (s *timeoutStore) ReplaceBallots(ctx context.Context, questionID string, participantID string, ballots []models.Ballot) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.ReplaceBallots(ctx, questionID, participantID, ballots))
+}
+
+func (s *timeoutStore) ReplaceVetoes(ctx context.Context, questionID string, participantID string, vetoes []models.Veto) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return timedOut(ctx, s.store.ReplaceVetoes(ctx, questionID, participantID, vetoes))
+}
+
+// timedOut makes sure the error of an operation whose context is done says
+// so. Backends don't all wrap the context's error in their own.
+func timedOut(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ctx.Err(), err)
+}
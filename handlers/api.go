@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix is where the versioned JSON API is mounted
+const APIPrefix = "/api/v1"
+
+// apiError is the envelope of every error the JSON API returns
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// staleUpdateError is the 409 Conflict answered when an update is based on
+// an older version of an item than the one stored
+type staleUpdateError struct {
+	apiError
+	Current any `json:"current"` // The item as it is now, to merge the update into
+}
+
+// newAPIError returns the JSON error envelope for a status
+func newAPIError(status int, message string) apiError {
+	return apiError{Error: apiErrorBody{
+		Status:  status,
+		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
+		Message: message,
+	}}
+}
+
+// abortWithAPIError responds with the JSON error envelope and stops the chain
+func abortWithAPIError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, newAPIError(status, message))
+}
+
+// abortWithStaleUpdate responds with 409 Conflict to an update that was
+// rejected with databases.ErrConflict, including the item as it is now
+func abortWithStaleUpdate(c *gin.Context, message string, current any) {
+	c.AbortWithStatusJSON(http.StatusConflict, staleUpdateError{
+		apiError: newAPIError(http.StatusConflict, message),
+		Current:  current,
+	})
+}
+
+// abortWithError responds with the JSON error envelope for API requests and
+// with the error page otherwise, so middleware can be shared by both
+func abortWithError(c *gin.Context, status int, title string, message string) {
+	if isAPIRequest(c) {
+		abortWithAPIError(c, status, message)
+		return
+	}
+
+	c.HTML(status, "error.html", gin.H{
+		"title":   title,
+		"message": message,
+	})
+	c.Abort()
+}
+
+// abortIfUnavailable responds with 504 Gateway Timeout if err is a store
+// operation that ran out of time, or 503 Service Unavailable if it was
+// canceled, reporting whether it did
+func abortIfUnavailable(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		abortWithError(c, http.StatusGatewayTimeout, "Timed out", "The database took too long to answer. Please try again in a moment.")
+	case errors.Is(err, context.Canceled):
+		abortWithError(c, http.StatusServiceUnavailable, "Unavailable", "The request was canceled before the database answered.")
+	default:
+		return false
+	}
+	return true
+}
+
+// abortWithStoreError responds to a failed store operation the way
+// abortIfUnavailable does if it timed out, with 409 Conflict if it was an
+// update someone else's change got in before, and with 500 Internal Server
+// Error and message otherwise
+func abortWithStoreError(c *gin.Context, err error, message string) {
+	if abortIfUnavailable(c, err) {
+		return
+	}
+	if errors.Is(err, databases.ErrConflict) {
+		abortWithError(c, http.StatusConflict, "Changed by someone else", "Someone else changed this at the same time. Reload the page and try again.")
+		return
+	}
+	abortWithError(c, http.StatusInternalServerError, "Error", message)
+}
+
+// updateVersion returns the version an update is based on: the one the
+// request gives, or the current one if it leaves it out
+func updateVersion(requested *int, current int) int {
+	if requested == nil {
+		return current
+	}
+	return *requested
+}
+
+func isAPIRequest(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, APIPrefix+"/")
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// apiURL returns the absolute API URL of a path, for Location headers
+func apiURL(path string) string {
+	return APIPrefix + path
+}
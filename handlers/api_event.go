@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventRequest is the body accepted when creating or updating an event
+type eventRequest struct {
+	Name            string     `json:"name" binding:"required"`
+	ModerateOptions bool       `json:"moderate_options,omitempty"` // New options wait for the organizer's approval
+	ClosesAt        *time.Time `json:"closes_at,omitempty"`        // Voting on every question closes then; never if left out
+	Version         *int       `json:"version,omitempty"`          // Only when updating: the version read, rejected if the event has changed since
+}
+
+// eventList is the body of a event listing
+type eventList struct {
+	Events     []models.Event `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Pass as cursor to get the next page, left out on the last
+}
+
+// createdEventResponse is an event as returned once, right after creation.
+// It is the only response that ever contains the organizer's admin token.
+type createdEventResponse struct {
+	models.Event
+	AdminToken string `json:"admin_token"`
+	AdminURL   string `json:"admin_url"`
+}
+
+func (h *Handler) APIListEvents(c *gin.Context) {
+	limit, cursor, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	events, next, err := h.store.ListEventsPage(c.Request.Context(), limit, cursor)
+	if errors.Is(err, databases.ErrInvalidCursor) {
+		abortWithAPIError(c, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch events")
+		return
+	}
+
+	if events == nil {
+		events = []models.Event{}
+	}
+	for i := range events {
+		events[i] = events[i].Redacted()
+	}
+	c.JSON(http.StatusOK, eventList{Events: events, NextCursor: next})
+}
+
+func (h *Handler) APICreateEvent(c *gin.Context) {
+	var request eventRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		abortWithAPIError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+
+	adminToken, err := utils.GenerateToken()
+	if err != nil {
+		abortWithAPIError(c, http.StatusInternalServerError, "Failed to generate admin token")
+		return
+	}
+
+	event := models.NewEvent(id, request.Name)
+	event.AdminToken = adminToken
+	event.ModerateOptions = request.ModerateOptions
+	event.ClosesAt = request.ClosesAt
+
+	if err := h.store.CreateEvent(c.Request.Context(), event); err != nil {
+		abortWithStoreError(c, err, "Failed to save event")
+		return
+	}
+
+	c.Header("Location", apiURL("/events/"+event.ID))
+	c.JSON(http.StatusCreated, createdEventResponse{
+		Event:      event,
+		AdminToken: adminToken,
+		AdminURL:   adminURL(c, &event),
+	})
+}
+
+func (h *Handler) APIGetEvent(c *gin.Context) {
+	event, ok := h.apiEvent(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, event.Redacted())
+}
+
+func (h *Handler) APIUpdateEvent(c *gin.Context) {
+	var request eventRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	eventID := c.Param("id")
+	event, ok := h.apiEvent(c, eventID)
+	if !ok {
+		return
+	}
+
+	update := models.NewEvent(eventID, request.Name)
+	update.ModerateOptions = request.ModerateOptions
+	update.ClosesAt = request.ClosesAt
+	update.Version = updateVersion(request.Version, event.Version)
+	err := h.store.UpdateEvent(c.Request.Context(), update)
+	if errors.Is(err, databases.ErrConflict) {
+		if current, ok := h.apiEvent(c, eventID); ok {
+			abortWithStaleUpdate(c, "The event was changed by someone else since you read it", current.Redacted())
+		}
+		return
+	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to update event")
+		return
+	}
+
+	event, ok = h.apiEvent(c, eventID)
+	if !ok {
+		return
+	}
+	redacted := event.Redacted()
+	h.publish(event.ID, "", eventUpdated, redacted)
+
+	c.JSON(http.StatusOK, redacted)
+}
+
+func (h *Handler) APIDeleteEvent(c *gin.Context) {
+	eventID := c.Param("id")
+	if err := h.store.DeleteEvent(c.Request.Context(), eventID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete event")
+		return
+	}
+	h.publish(eventID, "", eventDeleted, deletedItem{ID: eventID})
+
+	c.Status(http.StatusNoContent)
+}
+
+// apiEvent fetches an event, responding with an API error if that fails
+func (h *Handler) apiEvent(c *gin.Context, eventID string) (*models.Event, bool) {
+	event, err := h.store.GetEvent(c.Request.Context(), eventID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return nil, false
+	}
+
+	if event == nil {
+		abortWithAPIError(c, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+
+	return event, true
+}
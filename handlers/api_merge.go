@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergeRequest is the body accepted when merging an option into another
+type mergeRequest struct {
+	IntoOptionID string `json:"into_option_id" binding:"required"` // The option the merged option duplicates
+}
+
+// APIMergeOption folds an option into the option it duplicates, moving its
+// votes and vetoes across, and responds with the option merged into
+func (h *Handler) APIMergeOption(c *gin.Context) {
+	var request mergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	option, ok := h.apiOption(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	question, ok := h.apiQuestion(c, option.QuestionID)
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	into, ok := h.apiOption(c, request.IntoOptionID)
+	if !ok {
+		return
+	}
+	if err := option.ValidateMerge(*into); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.mergeOption(c.Request.Context(), question, *option, into); err != nil {
+		abortWithStoreError(c, err, "Failed to merge option")
+		return
+	}
+
+	// Count the moved votes
+	merged, ok := h.apiOption(c, into.ID)
+	if !ok {
+		return
+	}
+	if question.ResultsHidden() {
+		*merged = merged.Redacted()
+	}
+	c.JSON(http.StatusOK, merged)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIListSuggestions lists the options of a question awaiting the organizer's
+// approval
+func (h *Handler) APIListSuggestions(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	optionPage(c, question.PendingOptions())
+}
+
+func (h *Handler) APIApproveOption(c *gin.Context) {
+	option, ok := h.apiSuggestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	option.Pending = false
+	if err := h.store.UpdateOption(c.Request.Context(), *option); err != nil {
+		abortWithStoreError(c, err, "Failed to approve option")
+		return
+	}
+	option.Version++
+	h.publishOption(c.Request.Context(), optionUpdated, *option)
+
+	c.JSON(http.StatusOK, option)
+}
+
+func (h *Handler) APIRejectOption(c *gin.Context) {
+	option, ok := h.apiSuggestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteOption(c.Request.Context(), option.ID); err != nil {
+		abortWithStoreError(c, err, "Failed to reject option")
+		return
+	}
+	h.publishOption(c.Request.Context(), optionDeleted, *option)
+
+	c.Status(http.StatusNoContent)
+}
+
+// apiSuggestion fetches an option awaiting approval on a question that is
+// still open, responding with an API error otherwise
+func (h *Handler) apiSuggestion(c *gin.Context, optionID string) (*models.Option, bool) {
+	option, ok := h.apiOption(c, optionID)
+	if !ok {
+		return nil, false
+	}
+
+	question, ok := h.apiQuestion(c, option.QuestionID)
+	if !ok {
+		return nil, false
+	}
+	if !h.requireOpen(c, question) {
+		return nil, false
+	}
+
+	if !option.Pending {
+		abortWithAPIError(c, http.StatusConflict, "This option is not awaiting approval")
+		return nil, false
+	}
+
+	return option, true
+}
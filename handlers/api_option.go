@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// optionRequest is the body accepted when creating or updating an option.
+// Options of availability questions need a slot, and their text is an
+// optional note; other options need text and take no slot.
+type optionRequest struct {
+	Text           string       `json:"text,omitempty"`
+	Slot           *models.Slot `json:"slot,omitempty"`
+	AllowDuplicate bool         `json:"allow_duplicate,omitempty"` // Only when creating: add it even if it looks like an existing option
+	Version        *int         `json:"version,omitempty"`         // Only when updating: the version read, rejected if the option has changed since
+}
+
+// duplicateOptionError is the 409 Conflict answered when a new option looks
+// like options the question already has
+type duplicateOptionError struct {
+	apiError
+	Duplicates []models.Option `json:"duplicates"`
+}
+
+// optionList is the body of a option listing
+type optionList struct {
+	Options    []models.Option `json:"options"`
+	NextCursor string          `json:"next_cursor,omitempty"` // Pass as cursor to get the next page, left out on the last
+}
+
+// optionPage responds with a page of options
+func optionPage(c *gin.Context, options []models.Option) {
+	ids := make([]string, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	start, end, next, ok := pageOf(c, ids)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, optionList{Options: append([]models.Option{}, options[start:end]...), NextCursor: next})
+}
+
+func (h *Handler) APIListOptions(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	optionPage(c, question.Redacted().Options)
+}
+
+func (h *Handler) APICreateOption(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+	pending, ok := h.admitOption(c, question)
+	if !ok {
+		return
+	}
+
+	var request optionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		abortWithAPIError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+
+	option := models.NewOption(id, question.ID, request.Text)
+	option.Slot = request.Slot
+	option.Pending = pending
+	option.CreatedAt = time.Now().UTC()
+	if err := question.ValidateOption(option); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if duplicates := question.Redacted().Duplicates(option); len(duplicates) > 0 && !request.AllowDuplicate {
+		c.AbortWithStatusJSON(http.StatusConflict, duplicateOptionError{
+			apiError: apiError{Error: apiErrorBody{
+				Status:  http.StatusConflict,
+				Code:    "conflict",
+				Message: "This looks like an option the question already has. Vote for it instead, or set allow_duplicate to add it anyway.",
+			}},
+			Duplicates: duplicates,
+		})
+		return
+	}
+
+	if err := h.store.AddOption(c.Request.Context(), question.ID, option); err != nil {
+		abortWithStoreError(c, err, "Failed to save option")
+		return
+	}
+	h.publishOption(c.Request.Context(), optionCreated, option)
+
+	c.Header("Location", apiURL("/options/"+option.ID))
+	c.JSON(http.StatusCreated, option)
+}
+
+func (h *Handler) APIGetOption(c *gin.Context) {
+	option, ok := h.apiOption(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	question, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), option.QuestionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+	if question == nil {
+		abortWithAPIError(c, http.StatusNotFound, "Question not found")
+		return
+	}
+
+	// A suggestion awaiting approval is only the organizer's to see
+	if option.Pending && !isOrganizer(c, event) {
+		abortWithAPIError(c, http.StatusNotFound, "Option not found")
+		return
+	}
+
+	if question.ResultsHidden() {
+		*option = option.Redacted()
+	}
+	c.JSON(http.StatusOK, option)
+}
+
+func (h *Handler) APIUpdateOption(c *gin.Context) {
+	var request optionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	option, ok := h.apiOption(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	question, ok := h.apiQuestion(c, option.QuestionID)
+	if !ok {
+		return
+	}
+
+	option.Text = request.Text
+	option.Slot = request.Slot
+	option.Version = updateVersion(request.Version, option.Version)
+	if err := question.ValidateOption(*option); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.store.UpdateOption(c.Request.Context(), *option)
+	if errors.Is(err, databases.ErrConflict) {
+		if current, ok := h.apiOption(c, option.ID); ok {
+			if question.ResultsHidden() {
+				*current = current.Redacted()
+			}
+			abortWithStaleUpdate(c, "The option was changed by someone else since you read it", current)
+		}
+		return
+	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to update option")
+		return
+	}
+	option.Version++
+	h.publishOption(c.Request.Context(), optionUpdated, *option)
+
+	if question.ResultsHidden() {
+		*option = option.Redacted()
+	}
+	c.JSON(http.StatusOK, option)
+}
+
+func (h *Handler) APIDeleteOption(c *gin.Context) {
+	option, ok := h.apiOption(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteOption(c.Request.Context(), option.ID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete option")
+		return
+	}
+	h.publishOption(c.Request.Context(), optionDeleted, *option)
+
+	c.Status(http.StatusNoContent)
+}
+
+// apiOption fetches an option, responding with an API error if that fails
+func (h *Handler) apiOption(c *gin.Context, optionID string) (*models.Option, bool) {
+	option, err := h.store.GetOption(c.Request.Context(), optionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch option")
+		return nil, false
+	}
+
+	if option == nil {
+		abortWithAPIError(c, http.StatusNotFound, "Option not found")
+		return nil, false
+	}
+
+	return option, true
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPageSize is how many items a listing returns if not asked for a
+	// number
+	defaultPageSize = 50
+	// maxPageSize is the most items a listing returns at once
+	maxPageSize = 100
+)
+
+// pageParams reads the limit and cursor query parameters of a paginated
+// listing, responding with an API error if the limit is not a whole number
+// from 1 to maxPageSize
+func pageParams(c *gin.Context) (int, string, bool) {
+	limit := defaultPageSize
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			abortWithAPIError(c, http.StatusBadRequest, fmt.Sprintf("limit must be a whole number from 1 to %d", maxPageSize))
+			return 0, "", false
+		}
+		limit = n
+	}
+
+	return limit, c.Query("cursor"), true
+}
+
+// pageOf picks a page out of a listing already in memory, given the IDs of
+// its items in order. It returns the bounds of the page and the cursor of the
+// next one, empty on the last page.
+func pageOf(c *gin.Context, ids []string) (int, int, string, bool) {
+	limit, cursor, ok := pageParams(c)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	start, end, next, err := databases.PageOf(ids, limit, cursor)
+	if err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, "Invalid cursor")
+		return 0, 0, "", false
+	}
+	return start, end, next, true
+}
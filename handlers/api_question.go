@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// questionRequest is the body accepted when creating or updating a question
+type questionRequest struct {
+	Text            string                `json:"text" binding:"required"`
+	Type            models.QuestionType   `json:"type,omitempty"` // Only when creating, plurality if left out
+	MaxSelections   int                   `json:"max_selections,omitempty"`
+	ScaleMin        int                   `json:"scale_min,omitempty"`        // Only when creating, 0 if left out
+	ScaleMax        int                   `json:"scale_max,omitempty"`        // Only when creating, 5 if both ends are left out
+	WinBy           models.ScoreStatistic `json:"win_by,omitempty"`           // mean if left out
+	TieBreak        models.TieBreak       `json:"tie_break,omitempty"`        // keep if left out
+	MinParticipants int                   `json:"min_participants,omitempty"` // No winner until this many have voted
+	Majority        models.Majority       `json:"majority,omitempty"`         // most if left out
+	MajorityPercent int                   `json:"majority_percent,omitempty"` // Supermajorities only, 67 if left out
+	VetoLimit       int                   `json:"veto_limit,omitempty"`       // Vetoes that rule an option out, no vetoes if left out
+	HideResults     bool                  `json:"hide_results,omitempty"`     // Keep vote counts and winners secret until voting closes
+	Locked          bool                  `json:"locked,omitempty"`           // Only the organizer can add options
+	ClosesAt        *time.Time            `json:"closes_at,omitempty"`        // Voting closes then, or when the event's does if earlier
+	Version         *int                  `json:"version,omitempty"`          // Only when updating: the version read, rejected if the question has changed since
+}
+
+// tieRequest is the body accepted when the organizer settles a tie
+type tieRequest struct {
+	OptionID string `json:"option_id" binding:"required"` // One of the tied options
+}
+
+// questionList is the body of a question listing
+type questionList struct {
+	Questions  []models.Question `json:"questions"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Pass as cursor to get the next page, left out on the last
+}
+
+func (h *Handler) APIListQuestions(c *gin.Context) {
+	event, ok := h.apiEvent(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	questions := event.Redacted().Questions
+	ids := make([]string, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+	start, end, next, ok := pageOf(c, ids)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, questionList{Questions: append([]models.Question{}, questions[start:end]...), NextCursor: next})
+}
+
+func (h *Handler) APICreateQuestion(c *gin.Context) {
+	event, ok := h.apiEvent(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var request questionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		abortWithAPIError(c, http.StatusInternalServerError, "Failed to generate ID")
+		return
+	}
+
+	question := models.NewQuestion(id, event.ID, request.Text)
+	question.Type = request.Type
+	question.MaxSelections = request.MaxSelections
+	question.ScaleMin, question.ScaleMax = request.ScaleMin, request.ScaleMax
+	question.WinBy = request.WinBy
+	question.TieBreak = request.TieBreak
+	question.MinParticipants = request.MinParticipants
+	question.Majority, question.MajorityPercent = request.Majority, request.MajorityPercent
+	question.VetoLimit = request.VetoLimit
+	question.HideResults = request.HideResults
+	question.Locked = request.Locked
+	question.ClosesAt = request.ClosesAt
+	question = question.WithDefaults()
+	if err := question.ValidateSettings(); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.AddQuestion(c.Request.Context(), event.ID, question); err != nil {
+		abortWithStoreError(c, err, "Failed to save question")
+		return
+	}
+	h.publishQuestion(questionCreated, question)
+
+	c.Header("Location", apiURL("/questions/"+question.ID))
+	c.JSON(http.StatusCreated, question)
+}
+
+func (h *Handler) APIGetQuestion(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, question.Redacted())
+}
+
+// APIGetResult counts the votes on a question, with the workings its type
+// shows on the question page
+func (h *Handler) APIGetResult(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if question.ResultsHidden() {
+		c.JSON(http.StatusOK, models.HiddenResult())
+		return
+	}
+
+	result := question.Result()
+	if question.Decided() {
+		// The winners were frozen when voting closed
+		result.Winners, result.Tie = question.Decision.Winners, question.Decision.Tie
+	}
+	if result.Winners == nil {
+		result.Winners = []models.Option{}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// APIBreakTie settles a decided tie left to the organizer, making one of the
+// tied options the winner
+func (h *Handler) APIBreakTie(c *gin.Context) {
+	var request tieRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !question.Decided() || !question.Decision.AwaitsOrganizer() {
+		abortWithAPIError(c, http.StatusConflict, "There is no tie for the organizer to break")
+		return
+	}
+
+	decision, err := question.Decision.BreakTie(request.OptionID)
+	if err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.UpdateDecision(c.Request.Context(), question.ID, decision); err != nil {
+		abortWithStoreError(c, err, "Failed to save decision")
+		return
+	}
+	question.Decision = &decision
+	h.publishQuestion(questionUpdated, *question)
+
+	c.JSON(http.StatusOK, decision)
+}
+
+func (h *Handler) APIUpdateQuestion(c *gin.Context) {
+	var request questionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	question.Text = request.Text
+	question.MaxSelections = request.MaxSelections
+	question.WinBy = request.WinBy
+	question.TieBreak = request.TieBreak
+	question.MinParticipants = request.MinParticipants
+	question.Majority, question.MajorityPercent = request.Majority, request.MajorityPercent
+	question.VetoLimit = request.VetoLimit
+	question.HideResults = request.HideResults
+	question.Locked = request.Locked
+	question.ClosesAt = request.ClosesAt
+	question.Version = updateVersion(request.Version, question.Version)
+	*question = question.WithDefaults()
+	if err := question.ValidateSettings(); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.store.UpdateQuestion(c.Request.Context(), *question)
+	if errors.Is(err, databases.ErrConflict) {
+		if current, ok := h.apiQuestion(c, question.ID); ok {
+			abortWithStaleUpdate(c, "The question was changed by someone else since you read it", current.Redacted())
+		}
+		return
+	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to update question")
+		return
+	}
+	question.Version++
+	h.publishQuestion(questionUpdated, *question)
+
+	c.JSON(http.StatusOK, question.Redacted())
+}
+
+// APIRevealResults shows everyone the results of a question that hides them,
+// before voting on it closes
+func (h *Handler) APIRevealResults(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !question.ResultsHidden() {
+		abortWithAPIError(c, http.StatusConflict, "The results of this question are not hidden")
+		return
+	}
+
+	question.ResultsRevealed = true
+	if err := h.store.UpdateQuestion(c.Request.Context(), *question); err != nil {
+		abortWithStoreError(c, err, "Failed to reveal results")
+		return
+	}
+	question.Version++
+	h.publishQuestion(questionUpdated, *question)
+
+	c.JSON(http.StatusOK, question)
+}
+
+func (h *Handler) APIDeleteQuestion(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteQuestion(c.Request.Context(), question.ID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete question")
+		return
+	}
+	h.publish(question.EventID, question.ID, questionDeleted, deletedItem{ID: question.ID, EventID: question.EventID})
+
+	c.Status(http.StatusNoContent)
+}
+
+// apiQuestion fetches a question, responding with an API error if that fails
+func (h *Handler) apiQuestion(c *gin.Context, questionID string) (*models.Question, bool) {
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return nil, false
+	}
+
+	if question == nil {
+		abortWithAPIError(c, http.StatusNotFound, "Question not found")
+		return nil, false
+	}
+
+	return question, true
+}
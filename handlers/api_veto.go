@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// vetoRequest is the body accepted when replacing the caller's vetoes
+type vetoRequest struct {
+	OptionIDs []string `json:"option_ids"` // Options the caller can't go along with, none to withdraw every veto
+}
+
+// vetoResponse is the caller's current vetoes on a question
+type vetoResponse struct {
+	QuestionID string   `json:"question_id"`
+	OptionIDs  []string `json:"option_ids"`
+}
+
+func (h *Handler) APIGetVetoes(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	var vetoes []models.Veto
+	if participant != nil {
+		vetoes = question.VetoesFor(participant.ID)
+	}
+
+	c.JSON(http.StatusOK, newVetoResponse(question.ID, vetoes))
+}
+
+// APIPutVetoes replaces the caller's vetoes on a question with the given options
+func (h *Handler) APIPutVetoes(c *gin.Context) {
+	var request vetoRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	vetoes := make([]models.Veto, 0, len(request.OptionIDs))
+	for _, optionID := range request.OptionIDs {
+		vetoes = append(vetoes, models.NewVeto(question.ID, *participant, optionID))
+	}
+	if err := question.ValidateVetoes(vetoes); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.ReplaceVetoes(c.Request.Context(), question.ID, participant.ID, vetoes); err != nil {
+		abortWithStoreError(c, err, "Failed to record vetoes")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.JSON(http.StatusOK, newVetoResponse(question.ID, vetoes))
+}
+
+func (h *Handler) APIDeleteVetoes(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	if participant != nil {
+		if err := h.store.ReplaceVetoes(c.Request.Context(), question.ID, participant.ID, nil); err != nil {
+			abortWithStoreError(c, err, "Failed to withdraw vetoes")
+			return
+		}
+		h.publishVotes(c.Request.Context(), question.ID)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func newVetoResponse(questionID string, vetoes []models.Veto) vetoResponse {
+	response := vetoResponse{QuestionID: questionID, OptionIDs: make([]string, 0, len(vetoes))}
+	for _, veto := range vetoes {
+		response.OptionIDs = append(response.OptionIDs, veto.OptionID)
+	}
+	return response
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// voteRequest is the body accepted when casting or changing a vote. On
+// ranked questions the options are listed from most to least preferred.
+// Availability and score questions take an answer or a score for every option
+// instead of a list.
+type voteRequest struct {
+	OptionIDs    []string                       `json:"option_ids,omitempty"`
+	Availability map[string]models.Availability `json:"availability,omitempty"` // Option ID -> yes, maybe or no
+	Scores       map[string]int                 `json:"scores,omitempty"`       // Option ID -> score on the question's scale
+}
+
+// voteResponse is the caller's current vote on a question, in order of
+// preference on ranked questions, with their answers on availability
+// questions and their scores on score questions
+type voteResponse struct {
+	QuestionID   string                         `json:"question_id"`
+	OptionIDs    []string                       `json:"option_ids"`
+	Availability map[string]models.Availability `json:"availability,omitempty"`
+	Scores       map[string]int                 `json:"scores,omitempty"`
+}
+
+// participantRequest is the body accepted when updating the caller's details
+type participantRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *Handler) APIGetVote(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	var ballots []models.Ballot
+	if participant != nil {
+		ballots = question.BallotsFor(participant.ID)
+	}
+
+	c.JSON(http.StatusOK, newVoteResponse(*question, ballots))
+}
+
+// APIPutVote replaces the caller's vote on a question with the given options
+func (h *Handler) APIPutVote(c *gin.Context) {
+	var request voteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	switch {
+	case question.Kind() == models.AvailabilityQuestion && request.Availability == nil:
+		abortWithAPIError(c, http.StatusBadRequest, "availability is required on availability questions")
+		return
+	case question.Kind() == models.ScoreQuestion && request.Scores == nil:
+		abortWithAPIError(c, http.StatusBadRequest, "scores is required on score questions")
+		return
+	case question.Kind() != models.AvailabilityQuestion && question.Kind() != models.ScoreQuestion && request.OptionIDs == nil:
+		abortWithAPIError(c, http.StatusBadRequest, "option_ids is required")
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	var ballots []models.Ballot
+	switch question.Kind() {
+	case models.AvailabilityQuestion:
+		ballots = availabilityBallots(*question, *participant, request.Availability)
+	case models.ScoreQuestion:
+		ballots = scoreBallots(*question, *participant, request.Scores)
+	default:
+		ballots = newBallots(*question, *participant, request.OptionIDs)
+	}
+	if err := question.ValidateBallots(ballots); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record vote")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.JSON(http.StatusOK, newVoteResponse(*question, ballots))
+}
+
+func (h *Handler) APIDeleteVote(c *gin.Context) {
+	question, ok := h.apiQuestion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	if participant != nil {
+		if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, nil); err != nil {
+			abortWithStoreError(c, err, "Failed to withdraw vote")
+			return
+		}
+		h.publishVotes(c.Request.Context(), question.ID)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) APIGetParticipant(c *gin.Context) {
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	if participant == nil {
+		abortWithAPIError(c, http.StatusNotFound, "Participant not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+func (h *Handler) APIUpdateParticipant(c *gin.Context) {
+	var request participantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if runes := []rune(name); len(runes) > maxNameLength {
+		abortWithAPIError(c, http.StatusBadRequest, "name is too long")
+		return
+	}
+	participant.Name = name
+
+	if err := h.store.SaveParticipant(c.Request.Context(), *participant); err != nil {
+		abortWithStoreError(c, err, "Failed to save participant")
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+func newVoteResponse(question models.Question, ballots []models.Ballot) voteResponse {
+	response := voteResponse{QuestionID: question.ID, OptionIDs: make([]string, 0, len(ballots))}
+	switch question.Kind() {
+	case models.AvailabilityQuestion:
+		response.Availability = make(map[string]models.Availability, len(ballots))
+	case models.ScoreQuestion:
+		response.Scores = make(map[string]int, len(ballots))
+	}
+
+	for _, ballot := range ballots {
+		response.OptionIDs = append(response.OptionIDs, ballot.OptionID)
+		if response.Availability != nil {
+			response.Availability[ballot.OptionID] = ballot.Availability
+		}
+		if response.Scores != nil {
+			response.Scores[ballot.OptionID] = ballot.Score
+		}
+	}
+	return response
+}
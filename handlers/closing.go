@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCloseInterval is how often the scheduler looks for questions to
+// close if CLOSE_INTERVAL doesn't say otherwise
+const DefaultCloseInterval = 30 * time.Second
+
+// GetCloseInterval returns how often the scheduler looks for questions to
+// close, from the CLOSE_INTERVAL environment variable (eg "1m") or the default
+func GetCloseInterval() time.Duration {
+	if value := os.Getenv("CLOSE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Ignoring invalid CLOSE_INTERVAL %q", value)
+	}
+	return DefaultCloseInterval
+}
+
+// CloseQuestions closes every question whose close time has passed, checking
+// every interval until ctx is done, so results are frozen even if nobody
+// visits the question
+func (h *Handler) CloseQuestions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.closeDueQuestions(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeDueQuestions closes the questions the store reports as due, loading
+// only those in full
+func (h *Handler) closeDueQuestions(ctx context.Context, now time.Time) {
+	questionIDs, err := h.store.ListDueQuestionIDs(ctx, now)
+	if err != nil {
+		log.Printf("Failed to list questions to close: %v", err)
+		return
+	}
+
+	for _, questionID := range questionIDs {
+		question, event, err := h.store.GetQuestionWithEvent(ctx, questionID)
+		if err != nil {
+			log.Printf("Failed to get question %s to close: %v", questionID, err)
+			continue
+		}
+		if question == nil {
+			continue
+		}
+
+		if err := h.closeIfDue(ctx, question, event, now); err != nil {
+			log.Printf("Failed to close question %s: %v", questionID, err)
+		}
+	}
+}
+
+// closeDue closes the event's questions whose close time has passed, updating
+// them in place
+func (h *Handler) closeDue(ctx context.Context, event *models.Event, now time.Time) error {
+	for i := range event.Questions {
+		if err := h.closeIfDue(ctx, &event.Questions[i], event, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeIfDue freezes the question's result if its close time has passed and
+// it is still open, updating it in place
+func (h *Handler) closeIfDue(ctx context.Context, question *models.Question, event *models.Event, now time.Time) error {
+	if !question.DueToClose(event, now) {
+		return nil
+	}
+
+	decision := question.Decide(now)
+	closed, err := h.store.CloseQuestion(ctx, question.ID, decision)
+	if err != nil {
+		return err
+	}
+	if !closed {
+		// Someone else got there first, and theirs is the decision that counts
+		stored, err := h.store.GetQuestion(ctx, question.ID)
+		if err != nil || stored == nil {
+			return err
+		}
+		question.Decision = stored.Decision
+		return nil
+	}
+
+	question.Decision = &decision
+	h.publishQuestion(questionUpdated, *question)
+	return nil
+}
+
+// questionClosed reports whether the question no longer takes votes or new
+// options, closing it first if its close time has just passed
+func (h *Handler) questionClosed(ctx context.Context, question *models.Question) (bool, error) {
+	if question.Decided() {
+		return true, nil
+	}
+
+	event, err := h.store.GetEvent(ctx, question.EventID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := h.closeIfDue(ctx, question, event, time.Now()); err != nil {
+		return false, err
+	}
+	return question.Decided(), nil
+}
+
+// requireOpen stops the request with 409 Conflict if the question no longer
+// takes votes or new options
+func (h *Handler) requireOpen(c *gin.Context, question *models.Question) bool {
+	closed, err := h.questionClosed(c.Request.Context(), question)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return false
+	}
+	if closed {
+		abortWithError(c, http.StatusConflict, "Decided", "Voting on this question has closed")
+		return false
+	}
+	return true
+}
+
+// closeTimeFromForm reads an optional close time from the closes_at
+// datetime-local input, a wall clock time in the time_zone input's time zone
+func closeTimeFromForm(c *gin.Context) (*time.Time, error) {
+	value := strings.TrimSpace(c.PostForm("closes_at"))
+	if value == "" {
+		return nil, nil
+	}
+
+	timeZone := strings.TrimSpace(c.PostForm("time_zone"))
+	location, err := time.LoadLocation(timeZone)
+	if err != nil || timeZone == "" {
+		return nil, errors.New("unknown time zone: " + timeZone)
+	}
+
+	closesAt, err := time.ParseInLocation(dateTimeInputLayout, value, location)
+	if err != nil {
+		return nil, errors.New("invalid close time: " + value)
+	}
+	closesAt = closesAt.UTC()
+	return &closesAt, nil
+}
+
+// closeTimeInput returns a close time as the closes_at input's value, in UTC,
+// or "" for none
+func closeTimeInput(closesAt *time.Time) string {
+	if closesAt == nil {
+		return ""
+	}
+	return closesAt.UTC().Format(dateTimeInputLayout)
+}
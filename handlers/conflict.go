@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// editChange is a setting an edit form was saved with that differs from the
+// one someone else saved while the form was open, shown side by side when
+// the edit is rejected so the organizer can merge the two
+type editChange struct {
+	Label  string
+	Theirs string
+	Yours  string
+}
+
+// editConflict is shown above an edit form whose save was rejected because
+// someone else saved the same item first, see templates/conflict.html
+type editConflict struct {
+	Item    string // What was edited, "event", "question" or "option"
+	EditURL string // The edit form of the item as it is now
+	Changes []editChange
+}
+
+// versionFromForm reads the version an edit form was filled in from, kept in
+// its hidden version input
+func versionFromForm(c *gin.Context) int {
+	version, _ := strconv.Atoi(c.PostForm("version"))
+	return version
+}
+
+// eventConflict shows the edit form of an event again after saving it was
+// rejected because someone else saved the event first. The form keeps the
+// edit, lists where it differs from theirs and is based on their version,
+// so saving it again replaces theirs.
+func (h *Handler) eventConflict(c *gin.Context, event models.Event) {
+	current, err := h.store.GetEvent(c.Request.Context(), event.ID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return
+	}
+	if current == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Event not found")
+		return
+	}
+
+	event.Version = current.Version
+	c.HTML(http.StatusConflict, "edit_event.html", gin.H{
+		"event":    event,
+		"closesAt": closeTimeInput(event.ClosesAt),
+		"conflict": editConflict{"event", "/events/" + event.ID + "/edit", eventChanges(*current, event)},
+	})
+}
+
+// questionConflict shows the edit form of a question again the way
+// eventConflict does for an event
+func (h *Handler) questionConflict(c *gin.Context, question models.Question) {
+	current, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), question.ID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+	if current == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Question not found")
+		return
+	}
+
+	question.Version = current.Version
+	// Whether voting has closed is not up to the form
+	question.Decision = current.Decision
+	page := editQuestionPage(event, &question)
+	page["conflict"] = editConflict{"question", "/questions/" + question.ID + "/edit", questionChanges(*current, question)}
+	c.HTML(http.StatusConflict, "edit_question.html", page)
+}
+
+// optionConflict shows the edit form of an option again the way
+// eventConflict does for an event
+func (h *Handler) optionConflict(c *gin.Context, option models.Option) {
+	current, err := h.store.GetOption(c.Request.Context(), option.ID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch option")
+		return
+	}
+	if current == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Option not found")
+		return
+	}
+
+	question, err := h.store.GetQuestion(c.Request.Context(), current.QuestionID)
+	if err != nil || question == nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	option.Version = current.Version
+	page := editOptionPage(question, &option)
+	page["conflict"] = editConflict{"option", "/options/" + option.ID + "/edit", optionChanges(*current, option)}
+	c.HTML(http.StatusConflict, "edit_option.html", page)
+}
+
+// differing keeps the changes whose two sides differ
+func differing(changes ...editChange) []editChange {
+	var differ []editChange
+	for _, change := range changes {
+		if change.Theirs != change.Yours {
+			differ = append(differ, change)
+		}
+	}
+	return differ
+}
+
+// eventChanges lists the settings of the edit form of an event that differ
+// between the stored event and the edit
+func eventChanges(theirs models.Event, yours models.Event) []editChange {
+	return differing(
+		editChange{"Name", theirs.Name, yours.Name},
+		editChange{"Hold new options for approval", yesNo(theirs.ModerateOptions), yesNo(yours.ModerateOptions)},
+		editChange{"Voting closes", closeTimeLabel(theirs.ClosesAt), closeTimeLabel(yours.ClosesAt)},
+	)
+}
+
+// questionChanges lists the settings of the edit form of a question that
+// differ between the stored question and the edit
+func questionChanges(theirs models.Question, yours models.Question) []editChange {
+	return differing(
+		editChange{"Question", theirs.Text, yours.Text},
+		editChange{"Most options one person may pick", countLabel(theirs.MaxSelections, "No limit"), countLabel(yours.MaxSelections, "No limit")},
+		editChange{"Winner", theirs.Statistic().Label(), yours.Statistic().Label()},
+		editChange{"If options tie for the win", theirs.TieBreakPolicy().Label(), yours.TieBreakPolicy().Label()},
+		editChange{"Voters needed", countLabel(theirs.MinParticipants, "Any number"), countLabel(yours.MinParticipants, "Any number")},
+		editChange{"The winner needs", majorityLabel(theirs), majorityLabel(yours)},
+		editChange{"Vetoes that rule an option out", countLabel(theirs.VetoLimit, "No vetoes"), countLabel(yours.VetoLimit, "No vetoes")},
+		editChange{"Hide results until voting closes", yesNo(theirs.HideResults), yesNo(yours.HideResults)},
+		editChange{"Locked", yesNo(theirs.Locked), yesNo(yours.Locked)},
+		editChange{"Voting closes", closeTimeLabel(theirs.ClosesAt), closeTimeLabel(yours.ClosesAt)},
+	)
+}
+
+// optionChanges lists what differs between the stored option and the edit
+func optionChanges(theirs models.Option, yours models.Option) []editChange {
+	return differing(
+		editChange{"Option", theirs.Label(), yours.Label()},
+	)
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+// countLabel shows a number setting, or none if it is 0 and so turned off
+func countLabel(count int, none string) string {
+	if count == 0 {
+		return none
+	}
+	return strconv.Itoa(count)
+}
+
+func majorityLabel(q models.Question) string {
+	if q.MajorityRule() == models.Supermajority {
+		return q.MajorityRule().Label() + " of " + strconv.Itoa(q.MajorityPercent) + "%"
+	}
+	return q.MajorityRule().Label()
+}
+
+// closeTimeLabel shows a close time in UTC, as the edit forms take it
+func closeTimeLabel(closesAt *time.Time) string {
+	if closesAt == nil {
+		return "Not set"
+	}
+	return closesAt.UTC().Format("Mon 2 Jan 2006 15:04") + " UTC"
+}
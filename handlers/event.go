@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 	"github.com/evoteum/planzoco/go/planzoco/databases"
 	"github.com/evoteum/planzoco/go/planzoco/models"
 	"github.com/evoteum/planzoco/go/planzoco/utils"
@@ -10,13 +13,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func ListEvents(c *gin.Context) {
-	events, err := databases.ListEvents()
+func (h *Handler) ListEvents(c *gin.Context) {
+	events, err := h.store.ListEvents(c.Request.Context())
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch events",
-		})
+		abortWithStoreError(c, err, "Failed to fetch events")
 		return
 	}
 
@@ -25,11 +25,11 @@ func ListEvents(c *gin.Context) {
 	})
 }
 
-func NewEventForm(c *gin.Context) {
+func (h *Handler) NewEventForm(c *gin.Context) {
 	c.HTML(http.StatusOK, "new_event.html", nil)
 }
 
-func CreateEvent(c *gin.Context) {
+func (h *Handler) CreateEvent(c *gin.Context) {
 	var event models.Event
 	if err := c.ShouldBind(&event); err != nil {
 		c.HTML(http.StatusBadRequest, "new_event.html", gin.H{"error": err.Error()})
@@ -44,23 +44,32 @@ func CreateEvent(c *gin.Context) {
 
 	event.ID = id
 
-	if err := databases.CreateEvent(event); err != nil {
+	// The organizer token is handed out once, as a cookie and an admin link
+	adminToken, err := utils.GenerateToken()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "new_event.html", gin.H{"error": "Failed to generate admin token"})
+		return
+	}
+	event.AdminToken = adminToken
+
+	if err := h.store.CreateEvent(c.Request.Context(), event); err != nil {
+		if abortIfUnavailable(c, err) {
+			return
+		}
 		c.HTML(http.StatusInternalServerError, "new_event.html", gin.H{"error": "Failed to save event"})
 		return
 	}
 
+	setCookie(c, organizerCookie(event.ID), adminToken, organizerCookieMaxAge)
 	c.Redirect(http.StatusFound, "/events/"+event.ID)
 }
 
-func GetEvent(c *gin.Context) {
+func (h *Handler) GetEvent(c *gin.Context) {
 	eventID := c.Param("id")
 
-	event, err := databases.GetEvent(eventID)
+	event, err := h.store.GetEvent(c.Request.Context(), eventID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch event",
-		})
+		abortWithStoreError(c, err, "Failed to fetch event")
 		return
 	}
 
@@ -72,23 +81,42 @@ func GetEvent(c *gin.Context) {
 		return
 	}
 
+	// Show results as decided straight away, rather than when the scheduler
+	// next looks
+	if err := h.closeDue(c.Request.Context(), event, time.Now()); err != nil {
+		log.Printf("Failed to close questions of event %s: %v", event.ID, err)
+	}
+
 	scheme := getScheme(c)
 	baseURL := fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+	organizer := isOrganizer(c, event)
+	var eventAdminURL string
+	if organizer {
+		eventAdminURL = adminURL(c, event)
+	}
+
+	redacted := event.Redacted()
 	c.HTML(http.StatusOK, "event.html", gin.H{
-		"event":   event,
-		"baseURL": baseURL,
+		"event":       &redacted,
+		"baseURL":     baseURL,
+		"isOrganizer": organizer,
+		"adminURL":    eventAdminURL,
+		"eventClosed": event.ClosesAt != nil && !time.Now().Before(*event.ClosesAt),
+
+		"questionTypes":   models.QuestionTypes,
+		"statistics":      models.ScoreStatistics,
+		"tieBreaks":       models.TieBreaks,
+		"majorities":      models.Majorities,
+		"majorityPercent": models.DefaultMajorityPercent,
 	})
 }
 
-func UpdateEventForm(c *gin.Context) {
+func (h *Handler) UpdateEventForm(c *gin.Context) {
 	eventID := c.Param("id")
 
-	event, err := databases.GetEvent(eventID)
+	event, err := h.store.GetEvent(c.Request.Context(), eventID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch event",
-		})
+		abortWithStoreError(c, err, "Failed to fetch event")
 		return
 	}
 
@@ -101,43 +129,60 @@ func UpdateEventForm(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "edit_event.html", gin.H{
-		"event": event,
+		"event":    event,
+		"closesAt": closeTimeInput(event.ClosesAt),
 	})
 }
 
-func UpdateEvent(c *gin.Context) {
+func (h *Handler) UpdateEvent(c *gin.Context) {
 	eventID := c.Param("id")
 
 	var event models.Event
-	if err := c.ShouldBind(&event); err != nil {
+	err := c.ShouldBind(&event)
+	event.Version = versionFromForm(c)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "edit_event.html", gin.H{"error": err.Error(), "event": event})
+		return
+	}
+	closesAt, err := closeTimeFromForm(c)
+	if err != nil {
 		c.HTML(http.StatusBadRequest, "edit_event.html", gin.H{"error": err.Error(), "event": event})
 		return
 	}
+	event.ClosesAt = closesAt
 
 	// Preserve the ID
 	event.ID = eventID
 
-	if err := databases.UpdateEvent(event); err != nil {
+	err = h.store.UpdateEvent(c.Request.Context(), event)
+	if errors.Is(err, databases.ErrConflict) {
+		h.eventConflict(c, event)
+		return
+	}
+	if err != nil {
+		if abortIfUnavailable(c, err) {
+			return
+		}
 		c.HTML(http.StatusInternalServerError, "edit_event.html", gin.H{
 			"error": "Failed to update event",
 			"event": event,
 		})
 		return
 	}
+	event.Version++
+	h.publish(event.ID, "", eventUpdated, event.Redacted())
 
 	c.Redirect(http.StatusFound, "/events/"+event.ID)
 }
 
-func DeleteEvent(c *gin.Context) {
+func (h *Handler) DeleteEvent(c *gin.Context) {
 	eventID := c.Param("id")
 
-	if err := databases.DeleteEvent(eventID); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to delete event",
-		})
+	if err := h.store.DeleteEvent(c.Request.Context(), eventID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete event")
 		return
 	}
+	h.publish(eventID, "", eventDeleted, deletedItem{ID: eventID})
 
 	c.Redirect(http.StatusFound, "/")
 }
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/pubsub"
+)
+
+// Handler serves the planzoco pages using the store it was created with, and
+// publishes every change through the broker for the live update streams
+type Handler struct {
+	store  databases.Store
+	broker pubsub.Broker
+}
+
+// New creates a Handler backed by the given store and broker
+func New(store databases.Store, broker pubsub.Broker) *Handler {
+	return &Handler{store: store, broker: broker}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/pubsub"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keepAliveInterval is how often an idle stream sends a ping, so proxies
+// don't close it
+const keepAliveInterval = 25 * time.Second
+
+// Kinds of live update, sent as the SSE event name
+const (
+	eventUpdated    = "event.updated"
+	eventDeleted    = "event.deleted"
+	questionCreated = "question.created"
+	questionUpdated = "question.updated"
+	questionDeleted = "question.deleted"
+	optionCreated   = "option.created"
+	optionUpdated   = "option.updated"
+	optionDeleted   = "option.deleted"
+	optionSuggested = "option.suggested"
+	votesChanged    = "votes.changed"
+)
+
+// deletedItem is the payload of the *.deleted updates, and of
+// option.suggested, which only names the option awaiting approval
+type deletedItem struct {
+	ID         string `json:"id"`
+	EventID    string `json:"event_id,omitempty"`
+	QuestionID string `json:"question_id,omitempty"`
+}
+
+// voteCounts is the payload of votes.changed, which vetoes changing sends too.
+// While the question's results are hidden it only says that something changed.
+type voteCounts struct {
+	QuestionID string              `json:"question_id"`
+	Votes      map[string]int      `json:"votes"`            // Option ID -> votes
+	Voters     map[string][]string `json:"voters"`           // Option ID -> display names
+	Vetoes     map[string]int      `json:"vetoes,omitempty"` // Option ID -> vetoes, on questions that take them
+	Hidden     bool                `json:"hidden,omitempty"` // Set instead of the counts while the question's results are hidden
+}
+
+// StreamEvent streams the updates to an event and all its questions
+func (h *Handler) StreamEvent(c *gin.Context) {
+	event, err := h.store.GetEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return
+	}
+	if event == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Event not found")
+		return
+	}
+
+	h.stream(c, eventTopic(event.ID))
+}
+
+// StreamQuestion streams the updates to one question and its options
+func (h *Handler) StreamQuestion(c *gin.Context) {
+	question, err := h.store.GetQuestion(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+	if question == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Question not found")
+		return
+	}
+
+	h.stream(c, questionTopic(question.ID))
+}
+
+func (h *Handler) stream(c *gin.Context, topic string) {
+	messages, unsubscribe := h.broker.Subscribe(topic)
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case message, ok := <-messages:
+			if !ok {
+				return false
+			}
+			c.SSEvent(message.Type, message.Data)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
+// publish sends an update to the event's stream and, when questionID is set,
+// to the question's stream too
+func (h *Handler) publish(eventID string, questionID string, kind string, data any) {
+	message := pubsub.Message{Type: kind, Data: data}
+	if questionID != "" {
+		h.broker.Publish(questionTopic(questionID), message)
+	}
+	h.broker.Publish(eventTopic(eventID), message)
+}
+
+// publishQuestion sends a question.created or question.updated update
+func (h *Handler) publishQuestion(kind string, question models.Question) {
+	h.publish(question.EventID, question.ID, kind, question.Redacted())
+}
+
+// publishOption sends an option.* update, looking up the event the option's
+// question belongs to. Options awaiting approval are only announced as
+// option.suggested, so nobody but the organizer learns what they say.
+func (h *Handler) publishOption(ctx context.Context, kind string, option models.Option) {
+	// Everyone else watching still wants the update if whoever made the
+	// change has gone
+	ctx = context.WithoutCancel(ctx)
+	question, err := h.store.GetQuestion(ctx, option.QuestionID)
+	if err != nil || question == nil {
+		log.Printf("Failed to publish %s for option %s: %v", kind, option.ID, err)
+		return
+	}
+
+	var data any = option
+	if question.ResultsHidden() {
+		data = option.Redacted()
+	}
+	if option.Pending && kind != optionDeleted {
+		kind = optionSuggested
+	}
+	if kind == optionDeleted || kind == optionSuggested {
+		data = deletedItem{ID: option.ID, EventID: question.EventID, QuestionID: option.QuestionID}
+	}
+	h.publish(question.EventID, question.ID, kind, data)
+}
+
+// publishVotes sends the current vote counts of a question
+func (h *Handler) publishVotes(ctx context.Context, questionID string) {
+	ctx = context.WithoutCancel(ctx) // See publishOption
+	question, err := h.store.GetQuestion(ctx, questionID)
+	if err != nil || question == nil {
+		log.Printf("Failed to publish %s for question %s: %v", votesChanged, questionID, err)
+		return
+	}
+
+	counts := voteCounts{
+		QuestionID: question.ID,
+		Votes:      make(map[string]int, len(question.Options)),
+		Voters:     make(map[string][]string, len(question.Options)),
+	}
+	if question.ResultsHidden() {
+		counts.Hidden = true
+		h.publish(question.EventID, question.ID, votesChanged, counts)
+		return
+	}
+	if question.AllowsVetoes() {
+		counts.Vetoes = make(map[string]int, len(question.Options))
+	}
+	// Pending options are only the organizer's to see, and nobody votes for
+	// them anyway
+	for _, option := range question.Redacted().Options {
+		counts.Votes[option.ID] = option.Votes
+		counts.Voters[option.ID] = question.Voters(option.ID)
+		if counts.Vetoes != nil {
+			counts.Vetoes[option.ID] = option.Vetoes
+		}
+	}
+	h.publish(question.EventID, question.ID, votesChanged, counts)
+}
+
+func eventTopic(eventID string) string {
+	return "event/" + eventID
+}
+
+func questionTopic(questionID string) string {
+	return "question/" + questionID
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MergeOption folds an option into the option picked on the question page,
+// which it duplicates, moving its votes and vetoes across
+func (h *Handler) MergeOption(c *gin.Context) {
+	option, question, ok := h.optionTarget(c)
+	if !ok {
+		return
+	}
+
+	into, err := h.store.GetOption(c.Request.Context(), c.PostForm("into_option_id"))
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch option")
+		return
+	}
+
+	if into == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pick the option to merge into"})
+		return
+	}
+
+	if err := option.ValidateMerge(*into); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.mergeOption(c.Request.Context(), question, *option, into); err != nil {
+		abortWithStoreError(c, err, "Failed to merge option")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// mergeOption moves the ballots and vetoes for an option to the option it
+// is merged into, without counting anyone twice, then deletes it. The option
+// merged into is approved if it was still pending.
+//
+// Each participant's ballots are replaced on their own, as when they vote, so
+// a vote for the merged option cast while this runs can be lost with it.
+func (h *Handler) mergeOption(ctx context.Context, question *models.Question, option models.Option, into *models.Option) error {
+	if into.Pending {
+		into.Pending = false
+		if err := h.store.UpdateOption(ctx, *into); err != nil {
+			return err
+		}
+		into.Version++
+		h.publishOption(ctx, optionUpdated, *into)
+	}
+
+	for participantID, ballots := range question.MergedBallots(option.ID, into.ID) {
+		if err := h.store.ReplaceBallots(ctx, question.ID, participantID, ballots); err != nil {
+			return err
+		}
+	}
+	for participantID, vetoes := range question.MergedVetoes(option.ID, into.ID) {
+		if err := h.store.ReplaceVetoes(ctx, question.ID, participantID, vetoes); err != nil {
+			return err
+		}
+	}
+
+	if err := h.store.DeleteOption(ctx, option.ID); err != nil {
+		return err
+	}
+	h.publishOption(ctx, optionDeleted, option)
+	h.publishVotes(ctx, question.ID)
+	return nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// admitOption stops the request with 403 Forbidden if the question is locked
+// and the caller is not the organizer. Otherwise it reports whether an option
+// the caller adds waits for approval, as it does on an event that moderates
+// options unless the organizer adds it.
+func (h *Handler) admitOption(c *gin.Context, question *models.Question) (bool, bool) {
+	event, err := h.store.GetEvent(c.Request.Context(), question.EventID)
+	if err != nil || event == nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return false, false
+	}
+
+	organizer := isOrganizer(c, event)
+	if question.Locked && !organizer {
+		abortWithError(c, http.StatusForbidden, "Locked", "This question is not taking new suggestions")
+		return false, false
+	}
+
+	return event.ModerateOptions && !organizer, true
+}
+
+// ApproveOption lets everyone see and vote for a suggested option
+func (h *Handler) ApproveOption(c *gin.Context) {
+	option, question, ok := h.optionTarget(c)
+	if !ok {
+		return
+	}
+
+	if !option.Pending {
+		c.JSON(http.StatusConflict, gin.H{"error": "This option is not awaiting approval"})
+		return
+	}
+
+	option.Pending = false
+	if err := h.store.UpdateOption(c.Request.Context(), *option); err != nil {
+		abortWithStoreError(c, err, "Failed to approve option")
+		return
+	}
+	option.Version++
+	h.publishOption(c.Request.Context(), optionUpdated, *option)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// RejectOption deletes a suggested option nobody but the organizer has seen
+func (h *Handler) RejectOption(c *gin.Context) {
+	option, question, ok := h.optionTarget(c)
+	if !ok {
+		return
+	}
+
+	if !option.Pending {
+		c.JSON(http.StatusConflict, gin.H{"error": "This option is not awaiting approval"})
+		return
+	}
+
+	if err := h.store.DeleteOption(c.Request.Context(), option.ID); err != nil {
+		abortWithStoreError(c, err, "Failed to reject option")
+		return
+	}
+	h.publishOption(c.Request.Context(), optionDeleted, *option)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIPath is where the OpenAPI document of the JSON API is served
+const OpenAPIPath = APIPrefix + "/openapi.json"
+
+// APIDocsPath is where the browsable API documentation is served
+const APIDocsPath = "/api/docs"
+
+// Who may call an operation, anyone if empty
+const (
+	authParticipant = "participant"
+	authOrganizer   = "organizer"
+)
+
+// apiOperation describes one endpoint of the JSON API. The OpenAPI document is
+// built from these, and the schemas of their bodies are read from the Go types
+// the handlers actually encode, so the document follows the models as they change.
+type apiOperation struct {
+	ID        string // operationId, also the anchor on the docs page
+	Method    string
+	Path      string // As registered with gin, relative to APIPrefix
+	Summary   string
+	Auth      string
+	Request   any // Zero value of the request body type, nil if there is none
+	Status    int
+	Response  any // Zero value of the response body type, nil if there is none
+	Location  bool
+	Paged     bool // Takes the limit and cursor query parameters
+	Versioned bool // Rejects updates based on an outdated version with 409 Conflict
+}
+
+var apiOperations = []apiOperation{
+	{ID: "listEvents", Method: http.MethodGet, Path: "/events", Summary: "List events, without their questions", Status: http.StatusOK, Response: eventList{}, Paged: true},
+	{ID: "createEvent", Method: http.MethodPost, Path: "/events", Summary: "Create an event", Request: eventRequest{}, Status: http.StatusCreated, Response: createdEventResponse{}, Location: true},
+	{ID: "getEvent", Method: http.MethodGet, Path: "/events/:id", Summary: "Get an event with its questions and options", Status: http.StatusOK, Response: models.Event{}},
+	{ID: "updateEvent", Method: http.MethodPut, Path: "/events/:id", Summary: "Rename an event, turn option moderation on or off, or change its close time", Auth: authOrganizer, Request: eventRequest{}, Status: http.StatusOK, Response: models.Event{}, Versioned: true},
+	{ID: "deleteEvent", Method: http.MethodDelete, Path: "/events/:id", Summary: "Delete an event and everything in it", Auth: authOrganizer, Status: http.StatusNoContent},
+
+	{ID: "listQuestions", Method: http.MethodGet, Path: "/events/:id/questions", Summary: "List the questions of an event", Status: http.StatusOK, Response: questionList{}, Paged: true},
+	{ID: "createQuestion", Method: http.MethodPost, Path: "/events/:id/questions", Summary: "Add a question to an event", Request: questionRequest{}, Status: http.StatusCreated, Response: models.Question{}, Location: true},
+	{ID: "getQuestion", Method: http.MethodGet, Path: "/questions/:id", Summary: "Get a question with its options", Status: http.StatusOK, Response: models.Question{}},
+	{ID: "updateQuestion", Method: http.MethodPut, Path: "/questions/:id", Summary: "Change the text, close time, selection limit, tie-break, quorum, majority, veto limit, hidden results or lock of a question", Auth: authOrganizer, Request: questionRequest{}, Status: http.StatusOK, Response: models.Question{}, Versioned: true},
+	{ID: "deleteQuestion", Method: http.MethodDelete, Path: "/questions/:id", Summary: "Delete a question and its options", Auth: authOrganizer, Status: http.StatusNoContent},
+	{ID: "getResult", Method: http.MethodGet, Path: "/questions/:id/result", Summary: "Count the votes on a question", Status: http.StatusOK, Response: models.Result{}},
+	{ID: "breakTie", Method: http.MethodPost, Path: "/questions/:id/tie", Summary: "Pick the winner of a decided tie left to the organizer", Auth: authOrganizer, Request: tieRequest{}, Status: http.StatusOK, Response: models.Decision{}},
+	{ID: "revealResults", Method: http.MethodPost, Path: "/questions/:id/reveal", Summary: "Show everyone the hidden results of a question before it closes", Auth: authOrganizer, Status: http.StatusOK, Response: models.Question{}},
+
+	{ID: "listOptions", Method: http.MethodGet, Path: "/questions/:id/options", Summary: "List the options of a question", Status: http.StatusOK, Response: optionList{}, Paged: true},
+	{ID: "createOption", Method: http.MethodPost, Path: "/questions/:id/options", Summary: "Suggest an option", Request: optionRequest{}, Status: http.StatusCreated, Response: models.Option{}, Location: true},
+	{ID: "getOption", Method: http.MethodGet, Path: "/options/:id", Summary: "Get an option", Status: http.StatusOK, Response: models.Option{}},
+	{ID: "updateOption", Method: http.MethodPut, Path: "/options/:id", Summary: "Change the text or slot of an option", Auth: authOrganizer, Request: optionRequest{}, Status: http.StatusOK, Response: models.Option{}, Versioned: true},
+	{ID: "deleteOption", Method: http.MethodDelete, Path: "/options/:id", Summary: "Delete an option", Auth: authOrganizer, Status: http.StatusNoContent},
+
+	{ID: "listSuggestions", Method: http.MethodGet, Path: "/questions/:id/suggestions", Summary: "List the options of a question awaiting approval", Auth: authOrganizer, Status: http.StatusOK, Response: optionList{}, Paged: true},
+	{ID: "approveOption", Method: http.MethodPost, Path: "/options/:id/approve", Summary: "Approve a suggested option so everyone can vote for it", Auth: authOrganizer, Status: http.StatusOK, Response: models.Option{}},
+	{ID: "rejectOption", Method: http.MethodPost, Path: "/options/:id/reject", Summary: "Reject a suggested option", Auth: authOrganizer, Status: http.StatusNoContent},
+	{ID: "mergeOption", Method: http.MethodPost, Path: "/options/:id/merge", Summary: "Merge an option into the option it duplicates, moving its votes", Auth: authOrganizer, Request: mergeRequest{}, Status: http.StatusOK, Response: models.Option{}},
+
+	{ID: "getVote", Method: http.MethodGet, Path: "/questions/:id/vote", Summary: "Get your vote on a question", Auth: authParticipant, Status: http.StatusOK, Response: voteResponse{}},
+	{ID: "castVote", Method: http.MethodPut, Path: "/questions/:id/vote", Summary: "Cast or change your vote on a question", Auth: authParticipant, Request: voteRequest{}, Status: http.StatusOK, Response: voteResponse{}},
+	{ID: "withdrawVote", Method: http.MethodDelete, Path: "/questions/:id/vote", Summary: "Withdraw your vote on a question", Auth: authParticipant, Status: http.StatusNoContent},
+
+	{ID: "getVetoes", Method: http.MethodGet, Path: "/questions/:id/vetoes", Summary: "Get the options you vetoed on a question", Auth: authParticipant, Status: http.StatusOK, Response: vetoResponse{}},
+	{ID: "putVetoes", Method: http.MethodPut, Path: "/questions/:id/vetoes", Summary: "Veto options you can't go along with", Auth: authParticipant, Request: vetoRequest{}, Status: http.StatusOK, Response: vetoResponse{}},
+	{ID: "withdrawVetoes", Method: http.MethodDelete, Path: "/questions/:id/vetoes", Summary: "Withdraw your vetoes on a question", Auth: authParticipant, Status: http.StatusNoContent},
+
+	{ID: "getParticipant", Method: http.MethodGet, Path: "/participant", Summary: "Get your participant details", Auth: authParticipant, Status: http.StatusOK, Response: models.Participant{}},
+	{ID: "updateParticipant", Method: http.MethodPut, Path: "/participant", Summary: "Change your display name", Auth: authParticipant, Request: participantRequest{}, Status: http.StatusOK, Response: models.Participant{}},
+
+	{ID: "getOpenAPI", Method: http.MethodGet, Path: "/openapi.json", Summary: "Get this OpenAPI document", Status: http.StatusOK},
+}
+
+// openAPIDocument is built once, the first time it is asked for
+var openAPIDocument = sync.OnceValue(buildOpenAPIDocument)
+
+// OpenAPISpec serves the OpenAPI document of the JSON API
+func OpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPIDocument())
+}
+
+// APIDocs renders a browsable page listing every API endpoint
+func APIDocs(c *gin.Context) {
+	c.HTML(http.StatusOK, "api_docs.html", gin.H{
+		"operations": apiOperations,
+		"prefix":     APIPrefix,
+		"specURL":    OpenAPIPath,
+	})
+}
+
+// UndocumentedRoutes returns the API routes registered with gin that have no
+// operation in the OpenAPI document, as "METHOD /path"
+func UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool, len(apiOperations))
+	for _, operation := range apiOperations {
+		documented[operation.Method+" "+APIPrefix+operation.Path] = true
+	}
+
+	var missing []string
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, APIPrefix+"/") {
+			continue
+		}
+		if key := route.Method + " " + route.Path; !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func buildOpenAPIDocument() gin.H {
+	schemas := newSchemaRegistry()
+	schemas.schemaFor(reflect.TypeOf(apiError{}))
+
+	paths := gin.H{}
+	for _, operation := range apiOperations {
+		path := openAPIPath(APIPrefix + operation.Path)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(operation.Method)] = schemas.operation(operation)
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "planzoco API",
+			"version":     strings.TrimPrefix(APIPrefix, "/api/"),
+			"description": "Create events, ask questions, suggest options and vote on them.",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas.components,
+			"securitySchemes": gin.H{
+				authOrganizer: gin.H{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "The admin_token returned when the event was created",
+				},
+				authParticipant: gin.H{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        participantHeader,
+					"description": "Returned the first time you vote or set your name. Without it a new participant is created.",
+				},
+			},
+		},
+	}
+}
+
+// schemaRegistry turns Go types into OpenAPI schemas, collecting every named
+// struct under components/schemas
+type schemaRegistry struct {
+	components gin.H
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: gin.H{}}
+}
+
+func (r *schemaRegistry) operation(operation apiOperation) gin.H {
+	result := gin.H{
+		"summary":     operation.Summary,
+		"operationId": operation.ID,
+		"tags":        []string{strings.Split(strings.TrimPrefix(operation.Path, "/"), "/")[0]},
+	}
+
+	var parameters []gin.H
+	for _, segment := range strings.Split(operation.Path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			parameters = append(parameters, gin.H{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   gin.H{"type": "string"},
+			})
+		}
+	}
+	if operation.Paged {
+		parameters = append(parameters,
+			gin.H{
+				"name":        "limit",
+				"in":          "query",
+				"description": fmt.Sprintf("How many items to return, %d if left out", defaultPageSize),
+				"schema":      gin.H{"type": "integer", "minimum": 1, "maximum": maxPageSize},
+			},
+			gin.H{
+				"name":        "cursor",
+				"in":          "query",
+				"description": "The next_cursor of the previous page, to get the page after it",
+				"schema":      gin.H{"type": "string"},
+			},
+		)
+	}
+	if parameters != nil {
+		result["parameters"] = parameters
+	}
+
+	if operation.Request != nil {
+		result["requestBody"] = gin.H{
+			"required": true,
+			"content":  gin.H{"application/json": gin.H{"schema": r.schemaFor(reflect.TypeOf(operation.Request))}},
+		}
+	}
+
+	success := gin.H{"description": http.StatusText(operation.Status)}
+	if operation.Response != nil {
+		success["content"] = gin.H{"application/json": gin.H{"schema": r.schemaFor(reflect.TypeOf(operation.Response))}}
+	} else if operation.Status == http.StatusOK {
+		success["content"] = gin.H{"application/json": gin.H{"schema": gin.H{"type": "object"}}}
+	}
+	if operation.Location {
+		success["headers"] = gin.H{"Location": gin.H{
+			"description": "URL of the created resource",
+			"schema":      gin.H{"type": "string"},
+		}}
+	}
+
+	responses := gin.H{fmt.Sprint(operation.Status): success}
+	errorStatuses := []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	if operation.Request != nil || operation.Paged {
+		errorStatuses = append(errorStatuses, http.StatusBadRequest)
+	}
+	if strings.Contains(operation.Path, ":") {
+		errorStatuses = append(errorStatuses, http.StatusNotFound)
+	}
+	if operation.Auth == authOrganizer {
+		errorStatuses = append(errorStatuses, http.StatusForbidden)
+		result["security"] = []gin.H{{authOrganizer: []string{}}}
+	}
+	if operation.Auth == authParticipant {
+		result["security"] = []gin.H{{authParticipant: []string{}}, {}}
+	}
+	for _, status := range errorStatuses {
+		responses[fmt.Sprint(status)] = gin.H{
+			"description": http.StatusText(status),
+			"content":     gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+	if operation.Versioned {
+		responses[fmt.Sprint(http.StatusConflict)] = gin.H{
+			"description": "Changed by someone else since the version the update is based on",
+			"content": gin.H{"application/json": gin.H{"schema": gin.H{"allOf": []gin.H{
+				{"$ref": "#/components/schemas/Error"},
+				{"type": "object", "properties": gin.H{"current": r.schemaFor(reflect.TypeOf(operation.Response))}},
+			}}}},
+		}
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *schemaRegistry) schemaFor(t reflect.Type) gin.H {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return gin.H{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return gin.H{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return gin.H{"type": "array", "items": r.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": r.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		name := schemaName(t)
+		if _, ok := r.components[name]; !ok {
+			// Register before descending, so recursive types terminate
+			r.components[name] = gin.H{}
+			r.components[name] = r.structSchema(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	default:
+		return gin.H{}
+	}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) gin.H {
+	properties := gin.H{}
+	var required []string
+	r.addFields(t, properties, &required)
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of a struct the way encoding/json sees them,
+// flattening embedded structs
+func (r *schemaRegistry) addFields(t reflect.Type, properties gin.H, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			r.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.schemaFor(field.Type)
+
+		// Request fields are required when gin validates them, response
+		// fields whenever encoding/json always writes them
+		if binding, ok := field.Tag.Lookup("binding"); ok {
+			if strings.Contains(binding, "required") {
+				*required = append(*required, name)
+			}
+		} else if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaName names the component of a struct: "Event" for models.Event,
+// "VoteRequest" for voteRequest, and "Error" for apiError
+func schemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// openAPIPath converts gin's ":id" path parameters to OpenAPI's "{id}"
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
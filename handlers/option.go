@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/evoteum/planzoco/go/planzoco/databases"
 	"github.com/evoteum/planzoco/go/planzoco/models"
 	"github.com/evoteum/planzoco/go/planzoco/utils"
@@ -9,15 +15,51 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func CreateOption(c *gin.Context) {
+// dateTimeInputLayout is the format of the datetime-local inputs slots and
+// close times are entered in
+const dateTimeInputLayout = "2006-01-02T15:04"
+
+func (h *Handler) CreateOption(c *gin.Context) {
 	questionID := c.Param("id")
 
-	var option models.Option
-	if err := c.ShouldBind(&option); err != nil {
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+	pending, ok := h.admitOption(c, question)
+	if !ok {
+		return
+	}
+
+	option, err := bindOption(c, *question)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Offer the options this looks like before splitting the vote with it
+	if duplicates := question.Redacted().Duplicates(option); len(duplicates) > 0 && c.PostForm("allow_duplicate") != "true" {
+		c.HTML(http.StatusConflict, "duplicate_option.html", gin.H{
+			"question":   question,
+			"option":     option,
+			"duplicates": duplicates,
+			"plurality":  question.Kind() == models.PluralityQuestion,
+			"hidden":     question.ResultsHidden(),
+			"form":       c.Request.PostForm,
+		})
+		return
+	}
+
 	id, err := utils.GenerateID()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate ID"})
@@ -26,24 +68,24 @@ func CreateOption(c *gin.Context) {
 	option.ID = id
 	option.QuestionID = questionID
 	option.Votes = 0
+	option.Pending = pending
+	option.CreatedAt = time.Now().UTC()
 
-	if err := databases.AddOption(questionID, option); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save option"})
+	if err := h.store.AddOption(c.Request.Context(), questionID, option); err != nil {
+		abortWithStoreError(c, err, "Failed to save option")
 		return
 	}
+	h.publishOption(c.Request.Context(), optionCreated, option)
 
 	c.Redirect(http.StatusFound, "/questions/"+questionID)
 }
 
-func UpdateOptionForm(c *gin.Context) {
+func (h *Handler) UpdateOptionForm(c *gin.Context) {
 	optionID := c.Param("id")
 
-	option, err := databases.GetOption(optionID)
+	option, err := h.store.GetOption(c.Request.Context(), optionID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch option",
-		})
+		abortWithStoreError(c, err, "Failed to fetch option")
 		return
 	}
 
@@ -56,28 +98,39 @@ func UpdateOptionForm(c *gin.Context) {
 	}
 
 	// Get the question for context
-	question, _, err := databases.GetQuestionWithEvent(option.QuestionID)
+	question, _, err := h.store.GetQuestionWithEvent(c.Request.Context(), option.QuestionID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch question",
-		})
+		abortWithStoreError(c, err, "Failed to fetch question")
 		return
 	}
 
-	c.HTML(http.StatusOK, "edit_option.html", gin.H{
-		"option":   option,
-		"question": question,
-	})
+	c.HTML(http.StatusOK, "edit_option.html", editOptionPage(question, option))
 }
 
-func UpdateOption(c *gin.Context) {
+// editOptionPage returns what the edit form of an option shows
+func editOptionPage(question *models.Question, option *models.Option) gin.H {
+	var start, end string
+	if option.Slot != nil {
+		slotStart, slotEnd := option.Slot.Local()
+		start, end = slotStart.Format(dateTimeInputLayout), slotEnd.Format(dateTimeInputLayout)
+	}
+
+	return gin.H{
+		"option":       option,
+		"question":     question,
+		"availability": question.Kind() == models.AvailabilityQuestion,
+		"start":        start,
+		"end":          end,
+	}
+}
+
+func (h *Handler) UpdateOption(c *gin.Context) {
 	optionID := c.Param("id")
 
 	// Get existing option to preserve question ID and votes
-	existingOption, err := databases.GetOption(optionID)
+	existingOption, err := h.store.GetOption(c.Request.Context(), optionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch option"})
+		abortWithStoreError(c, err, "Failed to fetch option")
 		return
 	}
 
@@ -86,52 +139,71 @@ func UpdateOption(c *gin.Context) {
 		return
 	}
 
-	var option models.Option
-	if err := c.ShouldBind(&option); err != nil {
+	question, err := h.store.GetQuestion(c.Request.Context(), existingOption.QuestionID)
+	if err != nil || question == nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	option, err := bindOption(c, *question)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Preserve ID, QuestionID, and votes
+	// Preserve ID, QuestionID, votes and whether it awaits approval
 	option.ID = optionID
 	option.QuestionID = existingOption.QuestionID
 	option.Votes = existingOption.Votes
+	option.Pending = existingOption.Pending
+	option.Version = versionFromForm(c)
 
-	if err := databases.UpdateOption(option); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update option"})
+	err = h.store.UpdateOption(c.Request.Context(), option)
+	if errors.Is(err, databases.ErrConflict) {
+		h.optionConflict(c, option)
 		return
 	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to update option")
+		return
+	}
+	option.Version++
+	h.publishOption(c.Request.Context(), optionUpdated, option)
 
 	c.Redirect(http.StatusFound, "/questions/"+option.QuestionID)
 }
 
-func DeleteOption(c *gin.Context) {
+func (h *Handler) DeleteOption(c *gin.Context) {
 	optionID := c.Param("id")
 
 	// Get the option first to know which question to redirect to
-	option, err := databases.GetOption(optionID)
+	option, err := h.store.GetOption(c.Request.Context(), optionID)
 	if err != nil || option == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch option"})
+		abortWithStoreError(c, err, "Failed to fetch option")
 		return
 	}
 
 	questionID := option.QuestionID
 
-	if err := databases.DeleteOption(optionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete option"})
+	if err := h.store.DeleteOption(c.Request.Context(), optionID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete option")
 		return
 	}
+	h.publishOption(c.Request.Context(), optionDeleted, *option)
 
 	c.Redirect(http.StatusFound, "/questions/"+questionID)
 }
 
-func VoteOption(c *gin.Context) {
+// VoteOption casts the participant's vote for an option of a plurality
+// question. With one vote per question, voting for another option changes the
+// participant's vote.
+func (h *Handler) VoteOption(c *gin.Context) {
 	optionID := c.Param("id")
 
 	// Get the option to find its question
-	option, err := databases.GetOption(optionID)
+	option, err := h.store.GetOption(c.Request.Context(), optionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch option"})
+		abortWithStoreError(c, err, "Failed to fetch option")
 		return
 	}
 
@@ -140,12 +212,422 @@ func VoteOption(c *gin.Context) {
 		return
 	}
 
-	questionID := option.QuestionID
+	question, err := h.store.GetQuestion(c.Request.Context(), option.QuestionID)
+	if err != nil || question == nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
 
-	if err := databases.VoteOption(optionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+	if !h.requireOpen(c, question) {
 		return
 	}
 
-	c.Redirect(http.StatusFound, "/questions/"+questionID)
+	// A single vote would replace every approval, ranking or score
+	if question.Kind() != models.PluralityQuestion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only plurality questions take a single vote"})
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	ballots := []models.Ballot{models.NewBallot(question.ID, *participant, option.ID)}
+	if err := question.ValidateBallots(ballots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record vote")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// WithdrawVote takes back the participant's vote for an option
+func (h *Handler) WithdrawVote(c *gin.Context) {
+	optionID := c.Param("id")
+
+	option, err := h.store.GetOption(c.Request.Context(), optionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch option")
+		return
+	}
+
+	if option == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Option not found"})
+		return
+	}
+
+	question, err := h.store.GetQuestion(c.Request.Context(), option.QuestionID)
+	if err != nil || question == nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	// Without a participant there is no vote to withdraw
+	if participant != nil {
+		var ballots []models.Ballot
+		for _, ballot := range question.BallotsFor(participant.ID) {
+			if ballot.OptionID != optionID {
+				// Close the gap the option leaves in a ranking
+				if ballot.Rank != 0 {
+					ballot.Rank = len(ballots) + 1
+				}
+				ballots = append(ballots, ballot)
+			}
+		}
+
+		if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+			abortWithStoreError(c, err, "Failed to withdraw vote")
+			return
+		}
+		h.publishVotes(c.Request.Context(), question.ID)
+	}
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// RankOptions replaces the participant's ranking on a ranked question.
+// The form sends rank[<option ID>] for every option; options left blank are
+// not ranked, and the chosen numbers only decide the order, so gaps are fine.
+func (h *Handler) RankOptions(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	if !question.Kind().IsRanked() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only ranked questions can be ranked"})
+		return
+	}
+
+	type choice struct {
+		optionID string
+		rank     int
+	}
+	var choices []choice
+	taken := make(map[int]bool)
+	for optionID, value := range c.PostFormMap("rank") {
+		if value == "" {
+			continue
+		}
+		rank, err := strconv.Atoi(value)
+		if err != nil || rank < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rank: " + value})
+			return
+		}
+		if taken[rank] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each rank can only be given to one option"})
+			return
+		}
+		taken[rank] = true
+		choices = append(choices, choice{optionID: optionID, rank: rank})
+	}
+	sort.Slice(choices, func(i, j int) bool {
+		return choices[i].rank < choices[j].rank
+	})
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	optionIDs := make([]string, len(choices))
+	for i, choice := range choices {
+		optionIDs[i] = choice.optionID
+	}
+	ballots := newBallots(*question, *participant, optionIDs)
+	if err := question.ValidateBallots(ballots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record ranking")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// ApproveOptions replaces the participant's approvals on an approval question
+// with the options ticked on the ballot form
+func (h *Handler) ApproveOptions(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	if question.Kind() != models.ApprovalQuestion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only approval questions take several options at once"})
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	ballots := newBallots(*question, *participant, c.PostFormArray("option_id"))
+	if err := question.ValidateBallots(ballots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record approvals")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// MarkAvailability replaces the participant's answers on an availability
+// question. The form sends availability[<option ID>] as yes, maybe or no for
+// every slot; slots left unanswered are skipped.
+func (h *Handler) MarkAvailability(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	if question.Kind() != models.AvailabilityQuestion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only availability questions take yes, maybe or no"})
+		return
+	}
+
+	answers := make(map[string]models.Availability)
+	for optionID, value := range c.PostFormMap("availability") {
+		if value != "" {
+			answers[optionID] = models.Availability(value)
+		}
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	ballots := availabilityBallots(*question, *participant, answers)
+	if err := question.ValidateBallots(ballots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record availability")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// ScoreOptions replaces the participant's scores on a score question. The
+// form sends score[<option ID>] for every option; options left blank are not
+// scored.
+func (h *Handler) ScoreOptions(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !h.requireOpen(c, question) {
+		return
+	}
+
+	if question.Kind() != models.ScoreQuestion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only score questions can be scored"})
+		return
+	}
+
+	scores := make(map[string]int)
+	for optionID, value := range c.PostFormMap("score") {
+		if value == "" {
+			continue
+		}
+		score, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid score: " + value})
+			return
+		}
+		scores[optionID] = score
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	ballots := scoreBallots(*question, *participant, scores)
+	if err := question.ValidateBallots(ballots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceBallots(c.Request.Context(), question.ID, participant.ID, ballots); err != nil {
+		abortWithStoreError(c, err, "Failed to record scores")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// bindOption reads an option from the form the way the question needs it: a
+// slot with an optional note for availability questions, and text otherwise
+func bindOption(c *gin.Context, question models.Question) (models.Option, error) {
+	if question.Kind() != models.AvailabilityQuestion {
+		var option models.Option
+		err := c.ShouldBind(&option)
+		return option, err
+	}
+
+	slot, err := slotFromForm(c)
+	if err != nil {
+		return models.Option{}, err
+	}
+
+	option := models.Option{Text: strings.TrimSpace(c.PostForm("text")), Slot: &slot}
+	return option, question.ValidateOption(option)
+}
+
+// slotFromForm reads a slot from the start and end datetime-local inputs,
+// which are wall clock times in the time_zone input's time zone
+func slotFromForm(c *gin.Context) (models.Slot, error) {
+	start, err := time.Parse(dateTimeInputLayout, c.PostForm("start"))
+	if err != nil {
+		return models.Slot{}, errors.New("invalid start: " + c.PostForm("start"))
+	}
+	end, err := time.Parse(dateTimeInputLayout, c.PostForm("end"))
+	if err != nil {
+		return models.Slot{}, errors.New("invalid end: " + c.PostForm("end"))
+	}
+	return models.NewSlot(start, end, strings.TrimSpace(c.PostForm("time_zone")))
+}
+
+// availabilityBallots creates a participant's ballots for their answers on an
+// availability question
+func availabilityBallots(question models.Question, participant models.Participant, answers map[string]models.Availability) []models.Ballot {
+	ballots := make([]models.Ballot, 0, len(answers))
+	for _, optionID := range answeredInOrder(question, answers) {
+		ballot := models.NewBallot(question.ID, participant, optionID)
+		ballot.Availability = answers[optionID]
+		ballots = append(ballots, ballot)
+	}
+	return ballots
+}
+
+// scoreBallots creates a participant's ballots for the scores they gave on a
+// score question
+func scoreBallots(question models.Question, participant models.Participant, scores map[string]int) []models.Ballot {
+	ballots := make([]models.Ballot, 0, len(scores))
+	for _, optionID := range answeredInOrder(question, scores) {
+		ballot := models.NewBallot(question.ID, participant, optionID)
+		ballot.Score = scores[optionID]
+		ballots = append(ballots, ballot)
+	}
+	return ballots
+}
+
+// answeredInOrder returns the IDs of the options answered, in the order of
+// the question's options. Answers for options the question does not have
+// come last, so ValidateBallots rejects them.
+func answeredInOrder[T any](question models.Question, answers map[string]T) []string {
+	var optionIDs, unknown []string
+	known := make(map[string]bool, len(question.Options))
+	for _, option := range question.Options {
+		known[option.ID] = true
+		if _, ok := answers[option.ID]; ok {
+			optionIDs = append(optionIDs, option.ID)
+		}
+	}
+	for optionID := range answers {
+		if !known[optionID] {
+			unknown = append(unknown, optionID)
+		}
+	}
+	sort.Strings(unknown)
+	return append(optionIDs, unknown...)
+}
+
+// newBallots creates a participant's ballots for the given options. On
+// ranked questions the options are in order of preference, and each
+// ballot is ranked by its position.
+func newBallots(question models.Question, participant models.Participant, optionIDs []string) []models.Ballot {
+	ballots := make([]models.Ballot, 0, len(optionIDs))
+	for i, optionID := range optionIDs {
+		ballot := models.NewBallot(question.ID, participant, optionID)
+		if question.Kind().IsRanked() {
+			ballot.Rank = i + 1
+		}
+		ballots = append(ballots, ballot)
+	}
+	return ballots
 }
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	organizerCookiePrefix = "planzoco_admin_"
+	organizerCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// ClaimOrganizer is the target of the admin link. It stores the organizer
+// token in a cookie, so this device can manage the event from then on.
+func (h *Handler) ClaimOrganizer(c *gin.Context) {
+	eventID := c.Param("id")
+
+	event, err := h.store.GetEvent(c.Request.Context(), eventID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return
+	}
+
+	if event == nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Not Found",
+			"message": "Event not found",
+		})
+		return
+	}
+
+	token := c.Param("token")
+	if !event.IsOrganizer(token) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"title":   "Forbidden",
+			"message": "This admin link is not valid",
+		})
+		return
+	}
+
+	setCookie(c, organizerCookie(eventID), token, organizerCookieMaxAge)
+	c.Redirect(http.StatusFound, "/events/"+eventID)
+}
+
+// RequireEventOrganizer only lets the organizer of the :id event through
+func (h *Handler) RequireEventOrganizer(c *gin.Context) {
+	event, err := h.store.GetEvent(c.Request.Context(), c.Param("id"))
+	if err == nil && event == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Event not found")
+		return
+	}
+
+	h.requireOrganizer(c, event, err)
+}
+
+// RequireQuestionOrganizer only lets the organizer of the event the :id
+// question belongs to through
+func (h *Handler) RequireQuestionOrganizer(c *gin.Context) {
+	question, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), c.Param("id"))
+	if err == nil && question == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Question not found")
+		return
+	}
+
+	h.requireOrganizer(c, event, err)
+}
+
+// RequireOptionOrganizer only lets the organizer of the event the :id option
+// belongs to through
+func (h *Handler) RequireOptionOrganizer(c *gin.Context) {
+	option, err := h.store.GetOption(c.Request.Context(), c.Param("id"))
+	if err == nil && option == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Option not found")
+		return
+	}
+	if err != nil {
+		h.requireOrganizer(c, nil, err)
+		return
+	}
+
+	_, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), option.QuestionID)
+	h.requireOrganizer(c, event, err)
+}
+
+func (h *Handler) requireOrganizer(c *gin.Context, event *models.Event, err error) {
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch event")
+		return
+	}
+
+	if event == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found", "Event not found")
+		return
+	}
+
+	if !isOrganizer(c, event) {
+		abortWithError(c, http.StatusForbidden, "Forbidden",
+			"Only the organizer of this event can do that. Open your admin link on this device first.")
+		return
+	}
+
+	c.Next()
+}
+
+// isOrganizer reports whether the request carries the event's organizer
+// token, either in the cookie set by the admin link or as a bearer token
+func isOrganizer(c *gin.Context, event *models.Event) bool {
+	if event == nil {
+		return false
+	}
+
+	if token := bearerToken(c); token != "" {
+		return event.IsOrganizer(token)
+	}
+
+	token, err := c.Cookie(organizerCookie(event.ID))
+	if err != nil {
+		return false
+	}
+
+	return event.IsOrganizer(token)
+}
+
+// adminURL returns the link that makes a device the organizer of an event
+func adminURL(c *gin.Context, event *models.Event) string {
+	return fmt.Sprintf("%s://%s/events/%s/admin/%s", getScheme(c), c.Request.Host, event.ID, event.AdminToken)
+}
+
+func organizerCookie(eventID string) string {
+	return organizerCookiePrefix + eventID
+}
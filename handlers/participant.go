@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	participantCookie       = "planzoco_participant"
+	participantHeader       = "X-Participant-Token"
+	participantCookieMaxAge = 365 * 24 * 60 * 60
+	maxNameLength           = 50
+)
+
+func (h *Handler) UpdateParticipant(c *gin.Context) {
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch participant")
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+	participant.Name = name
+
+	if err := h.store.SaveParticipant(c.Request.Context(), *participant); err != nil {
+		abortWithStoreError(c, err, "Failed to save your name")
+		return
+	}
+
+	c.Redirect(http.StatusFound, localRedirect(c.PostForm("redirect")))
+}
+
+// currentParticipant returns the participant identified by the request's
+// cookie or X-Participant-Token header, or nil if the request does not carry
+// a known one
+func (h *Handler) currentParticipant(c *gin.Context) (*models.Participant, error) {
+	participantID := c.GetHeader(participantHeader)
+	if participantID == "" {
+		participantID, _ = c.Cookie(participantCookie)
+	}
+	if participantID == "" {
+		return nil, nil
+	}
+
+	return h.store.GetParticipant(c.Request.Context(), participantID)
+}
+
+// ensureParticipant returns the current participant, registering a new
+// anonymous one and handing out its cookie if there is none yet
+func (h *Handler) ensureParticipant(c *gin.Context) (*models.Participant, error) {
+	participant, err := h.currentParticipant(c)
+	if err != nil || participant != nil {
+		return participant, err
+	}
+
+	id, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	newParticipant := models.NewParticipant(id, "")
+	if err := h.store.SaveParticipant(c.Request.Context(), newParticipant); err != nil {
+		return nil, err
+	}
+
+	// Browsers keep the cookie, API clients are expected to keep the header
+	setCookie(c, participantCookie, id, participantCookieMaxAge)
+	c.Header(participantHeader, id)
+	return &newParticipant, nil
+}
+
+func setCookie(c *gin.Context, name string, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.Request.Header.Get("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/", "", secure, true)
+}
+
+// localRedirect only lets through paths on this site, so forms cannot be
+// used to bounce people to somewhere else
+func localRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
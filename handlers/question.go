@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"time"
 	"github.com/evoteum/planzoco/go/planzoco/databases"
 	"github.com/evoteum/planzoco/go/planzoco/models"
 	"github.com/evoteum/planzoco/go/planzoco/utils"
@@ -9,7 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func CreateQuestion(c *gin.Context) {
+func (h *Handler) CreateQuestion(c *gin.Context) {
 	eventID := c.Param("id")
 
 	var question models.Question
@@ -25,24 +28,27 @@ func CreateQuestion(c *gin.Context) {
 	}
 	question.ID = id
 	question.EventID = eventID
+	question = question.WithDefaults()
+	if err := question.ValidateSettings(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := databases.AddQuestion(eventID, question); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save question"})
+	if err := h.store.AddQuestion(c.Request.Context(), eventID, question); err != nil {
+		abortWithStoreError(c, err, "Failed to save question")
 		return
 	}
+	h.publishQuestion(questionCreated, question)
 
 	c.Redirect(http.StatusFound, "/events/"+eventID)
 }
 
-func GetQuestion(c *gin.Context) {
+func (h *Handler) GetQuestion(c *gin.Context) {
 	questionID := c.Param("id")
 
-	question, event, err := databases.GetQuestionWithEvent(questionID)
+	question, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), questionID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch question",
-		})
+		abortWithStoreError(c, err, "Failed to fetch question")
 		return
 	}
 
@@ -54,21 +60,89 @@ func GetQuestion(c *gin.Context) {
 		return
 	}
 
+	// Show the result as decided straight away, rather than when the
+	// scheduler next looks
+	if err := h.closeIfDue(c.Request.Context(), question, event, time.Now()); err != nil {
+		log.Printf("Failed to close question %s: %v", question.ID, err)
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch participant")
+		return
+	}
+
+	// Mark the options this participant voted for, how they ranked or scored
+	// them, whether they are available and which they vetoed
+	myVotes := make(map[string]bool)
+	myRanks := make(map[string]int)
+	myAnswers := make(map[string]models.Availability)
+	myScores := make(map[string]int)
+	myVetoes := make(map[string]bool)
+	if participant != nil {
+		for _, ballot := range question.BallotsFor(participant.ID) {
+			myVotes[ballot.OptionID] = true
+			myRanks[ballot.OptionID] = ballot.Rank
+			myAnswers[ballot.OptionID] = ballot.Availability
+			myScores[ballot.OptionID] = ballot.Score
+		}
+		for _, veto := range question.VetoesFor(participant.ID) {
+			myVetoes[veto.OptionID] = true
+		}
+	}
+
+	// Only the organizer sees the suggestions awaiting approval
+	organizer := isOrganizer(c, event)
+	var pending []models.Option
+	if organizer {
+		pending = question.PendingOptions()
+	}
+
+	// Everyone still sees their own ballot above, but nobody else's until the
+	// results are out
+	result := question.Result()
+	if question.ResultsHidden() {
+		result = models.HiddenResult()
+	}
+	*question = question.Redacted()
+
+	rankChoices := make([]int, len(question.Options))
+	for i := range rankChoices {
+		rankChoices[i] = i + 1
+	}
+
 	c.HTML(http.StatusOK, "question.html", gin.H{
-		"event":    event,
-		"question": question,
+		"event":          event,
+		"question":       question,
+		"participant":    participant,
+		"myVotes":        myVotes,
+		"myRanks":        myRanks,
+		"myAnswers":      myAnswers,
+		"myScores":       myScores,
+		"myVetoes":       myVetoes,
+		"ranked":         question.Kind().IsRanked(),
+		"approval":       question.Kind() == models.ApprovalQuestion,
+		"availability":   question.Kind() == models.AvailabilityQuestion,
+		"availabilities": models.Availabilities,
+		"score":          question.Kind() == models.ScoreQuestion,
+		"matrix":         question.AvailabilityMatrix(),
+		"maxVotes":       question.MaxVotes(),
+		"rankChoices":    rankChoices,
+		"result":         result,
+		"deadline":       question.Deadline(event),
+		"hidden":         question.ResultsHidden(),
+		"pending":        pending,
+		"isOrganizer":    organizer,
+		"path":           c.Request.URL.Path,
 	})
 }
 
-func UpdateQuestionForm(c *gin.Context) {
+func (h *Handler) UpdateQuestionForm(c *gin.Context) {
 	questionID := c.Param("id")
 
-	question, event, err := databases.GetQuestionWithEvent(questionID)
+	question, event, err := h.store.GetQuestionWithEvent(c.Request.Context(), questionID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Error",
-			"message": "Failed to fetch question",
-		})
+		abortWithStoreError(c, err, "Failed to fetch question")
 		return
 	}
 
@@ -80,19 +154,29 @@ func UpdateQuestionForm(c *gin.Context) {
 		return
 	}
 
-	c.HTML(http.StatusOK, "edit_question.html", gin.H{
-		"event":    event,
-		"question": question,
-	})
+	c.HTML(http.StatusOK, "edit_question.html", editQuestionPage(event, question))
 }
 
-func UpdateQuestion(c *gin.Context) {
+// editQuestionPage returns what the edit form of a question shows
+func editQuestionPage(event *models.Event, question *models.Question) gin.H {
+	return gin.H{
+		"event":           event,
+		"question":        question,
+		"statistics":      models.ScoreStatistics,
+		"tieBreaks":       models.TieBreaks,
+		"majorities":      models.Majorities,
+		"majorityPercent": models.DefaultMajorityPercent,
+		"closesAt":        closeTimeInput(question.ClosesAt),
+	}
+}
+
+func (h *Handler) UpdateQuestion(c *gin.Context) {
 	questionID := c.Param("id")
 
 	// Get existing question to preserve eventID
-	existingQuestion, _, err := databases.GetQuestionWithEvent(questionID)
+	existingQuestion, _, err := h.store.GetQuestionWithEvent(c.Request.Context(), questionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch question"})
+		abortWithStoreError(c, err, "Failed to fetch question")
 		return
 	}
 
@@ -106,38 +190,133 @@ func UpdateQuestion(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	question.ClosesAt, err = closeTimeFromForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Preserve ID and EventID
+	// Preserve ID, EventID, type, scale, whether the results were revealed
+	// and, once decided, close time
 	question.ID = questionID
 	question.EventID = existingQuestion.EventID
+	question.Type = existingQuestion.Type
+	question.ScaleMin, question.ScaleMax = existingQuestion.ScaleMin, existingQuestion.ScaleMax
+	question.ResultsRevealed = existingQuestion.ResultsRevealed
+	if existingQuestion.Decided() {
+		question.ClosesAt = existingQuestion.ClosesAt
+	}
+	question = question.WithDefaults()
+	if err := question.ValidateSettings(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Preserve existing options
 	question.Options = existingQuestion.Options
+	question.Version = versionFromForm(c)
+
+	err = h.store.UpdateQuestion(c.Request.Context(), question)
+	if errors.Is(err, databases.ErrConflict) {
+		h.questionConflict(c, question)
+		return
+	}
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to update question")
+		return
+	}
+	question.Version++
+	h.publishQuestion(questionUpdated, question)
+
+	c.Redirect(http.StatusFound, "/questions/"+questionID)
+}
+
+// BreakTie settles a decided tie left to the organizer, making the option
+// picked on the question page the winner
+func (h *Handler) BreakTie(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !question.Decided() || !question.Decision.AwaitsOrganizer() {
+		c.JSON(http.StatusConflict, gin.H{"error": "There is no tie for the organizer to break"})
+		return
+	}
+
+	decision, err := question.Decision.BreakTie(c.PostForm("option_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.UpdateDecision(c.Request.Context(), question.ID, decision); err != nil {
+		abortWithStoreError(c, err, "Failed to save decision")
+		return
+	}
+	question.Decision = &decision
+	h.publishQuestion(questionUpdated, *question)
+
+	c.Redirect(http.StatusFound, "/questions/"+questionID)
+}
+
+// RevealResults shows everyone the results of a question that hides them,
+// before voting on it closes
+func (h *Handler) RevealResults(c *gin.Context) {
+	questionID := c.Param("id")
+
+	question, err := h.store.GetQuestion(c.Request.Context(), questionID)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return
+	}
+
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !question.ResultsHidden() {
+		c.JSON(http.StatusConflict, gin.H{"error": "The results of this question are not hidden"})
+		return
+	}
 
-	if err := databases.UpdateQuestion(question); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
+	question.ResultsRevealed = true
+	if err := h.store.UpdateQuestion(c.Request.Context(), *question); err != nil {
+		abortWithStoreError(c, err, "Failed to reveal results")
 		return
 	}
+	question.Version++
+	h.publishQuestion(questionUpdated, *question)
 
 	c.Redirect(http.StatusFound, "/questions/"+questionID)
 }
 
-func DeleteQuestion(c *gin.Context) {
+func (h *Handler) DeleteQuestion(c *gin.Context) {
 	questionID := c.Param("id")
 
 	// Get the question first to know which event to redirect to
-	question, _, err := databases.GetQuestionWithEvent(questionID)
+	question, _, err := h.store.GetQuestionWithEvent(c.Request.Context(), questionID)
 	if err != nil || question == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch question"})
+		abortWithStoreError(c, err, "Failed to fetch question")
 		return
 	}
 
 	eventID := question.EventID
 
-	if err := databases.DeleteQuestion(questionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+	if err := h.store.DeleteQuestion(c.Request.Context(), questionID); err != nil {
+		abortWithStoreError(c, err, "Failed to delete question")
 		return
 	}
+	h.publish(eventID, questionID, questionDeleted, deletedItem{ID: questionID, EventID: eventID})
 
 	c.Redirect(http.StatusFound, "/events/"+eventID)
 }
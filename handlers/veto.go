@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evoteum/planzoco/go/planzoco/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VetoOption marks an option as one the participant can't go along with,
+// next to any other options they have vetoed on the question
+func (h *Handler) VetoOption(c *gin.Context) {
+	option, question, ok := h.optionTarget(c)
+	if !ok {
+		return
+	}
+
+	participant, err := h.ensureParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	vetoes := []models.Veto{models.NewVeto(question.ID, *participant, option.ID)}
+	for _, veto := range question.VetoesFor(participant.ID) {
+		if veto.OptionID != option.ID {
+			vetoes = append(vetoes, veto)
+		}
+	}
+	if err := question.ValidateVetoes(vetoes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.ReplaceVetoes(c.Request.Context(), question.ID, participant.ID, vetoes); err != nil {
+		abortWithStoreError(c, err, "Failed to record veto")
+		return
+	}
+	h.publishVotes(c.Request.Context(), question.ID)
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// WithdrawVeto takes back the participant's veto of an option
+func (h *Handler) WithdrawVeto(c *gin.Context) {
+	option, question, ok := h.optionTarget(c)
+	if !ok {
+		return
+	}
+
+	participant, err := h.currentParticipant(c)
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to identify participant")
+		return
+	}
+
+	// Without a participant there is no veto to withdraw
+	if participant != nil {
+		var vetoes []models.Veto
+		for _, veto := range question.VetoesFor(participant.ID) {
+			if veto.OptionID != option.ID {
+				vetoes = append(vetoes, veto)
+			}
+		}
+
+		if err := h.store.ReplaceVetoes(c.Request.Context(), question.ID, participant.ID, vetoes); err != nil {
+			abortWithStoreError(c, err, "Failed to withdraw veto")
+			return
+		}
+		h.publishVotes(c.Request.Context(), question.ID)
+	}
+
+	c.Redirect(http.StatusFound, "/questions/"+question.ID)
+}
+
+// optionTarget looks up the option in the path and its question, stopping the
+// request if either is missing or voting on the question has closed
+func (h *Handler) optionTarget(c *gin.Context) (*models.Option, *models.Question, bool) {
+	option, err := h.store.GetOption(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithStoreError(c, err, "Failed to fetch option")
+		return nil, nil, false
+	}
+
+	if option == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Option not found"})
+		return nil, nil, false
+	}
+
+	question, err := h.store.GetQuestion(c.Request.Context(), option.QuestionID)
+	if err != nil || question == nil {
+		abortWithStoreError(c, err, "Failed to fetch question")
+		return nil, nil, false
+	}
+
+	if !h.requireOpen(c, question) {
+		return nil, nil, false
+	}
+
+	return option, question, true
+}
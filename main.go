@@ -1,16 +1,76 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/handlers"
+	"github.com/evoteum/planzoco/go/planzoco/pubsub"
 	"github.com/evoteum/planzoco/go/planzoco/routes"
+	"github.com/evoteum/planzoco/go/planzoco/utils"
 )
 
 func main() {
-	if err := databases.InitDB(); err != nil {
+	store, err := databases.NewStore()
+	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
-	r := routes.SetupRoutes()
+	// Events created before admin tokens existed have none, so nobody can
+	// manage them until an operator issues one
+	if len(os.Args) > 1 && os.Args[1] == "issue-admin-tokens" {
+		if err := issueAdminTokens(context.Background(), store, os.Stdout); err != nil {
+			log.Fatal("Failed to issue admin tokens:", err)
+		}
+		return
+	}
+
+	// Finish deleting anything a previous run left half deleted
+	if resumer, ok := store.(databases.DeleteResumer); ok {
+		go func() {
+			if err := resumer.ResumeDeletes(context.Background()); err != nil {
+				log.Printf("Failed to resume deletes: %v", err)
+			}
+		}()
+	}
+
+	broker := pubsub.NewHub()
+
+	// Close questions whose close time has passed, whether or not anyone is
+	// looking at them
+	go handlers.New(store, broker).CloseQuestions(context.Background(), handlers.GetCloseInterval())
+
+	r := routes.SetupRoutes(store, broker)
 	r.Run(":8080")
 }
+
+// issueAdminTokens gives every event without an admin token a new one, and
+// writes each event's ID and the path of its admin link to out
+func issueAdminTokens(ctx context.Context, store databases.Store, out io.Writer) error {
+	events, err := store.ListEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.AdminToken != "" {
+			continue
+		}
+
+		token, err := utils.GenerateToken()
+		if err != nil {
+			return err
+		}
+		set, err := store.SetAdminToken(ctx, event.ID, token)
+		if err != nil {
+			return err
+		}
+		if set {
+			fmt.Fprintf(out, "%s\t/events/%s/admin/%s\n", event.ID, event.ID, token)
+		}
+	}
+	return nil
+}
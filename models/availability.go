@@ -0,0 +1,242 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	// Slots can be in any time zone, whatever the host has installed
+	_ "time/tzdata"
+)
+
+// maybeCredit is how much a "maybe" counts towards a slot's score, where a
+// "yes" counts 1
+const maybeCredit = 0.5
+
+// Availability is a participant's answer for one slot of an availability question
+type Availability string
+
+const (
+	AvailableYes   Availability = "yes"
+	AvailableMaybe Availability = "maybe"
+	AvailableNo    Availability = "no"
+)
+
+// Availabilities lists the answers in the order they are offered
+var Availabilities = []Availability{AvailableYes, AvailableMaybe, AvailableNo}
+
+// Valid reports whether a is a known answer
+func (a Availability) Valid() bool {
+	return a == AvailableYes || a == AvailableMaybe || a == AvailableNo
+}
+
+// Label returns the answer as shown to people, eg "Maybe"
+func (a Availability) Label() string {
+	switch a {
+	case AvailableYes:
+		return "Yes"
+	case AvailableMaybe:
+		return "Maybe"
+	case AvailableNo:
+		return "No"
+	default:
+		return string(a)
+	}
+}
+
+// Slot is the date and time an option of an availability question stands for
+type Slot struct {
+	Start    time.Time `json:"start" dynamodbav:"start"`
+	End      time.Time `json:"end" dynamodbav:"end"`
+	TimeZone string    `json:"time_zone" dynamodbav:"time_zone"` // IANA name, eg "Europe/London"
+}
+
+// NewSlot creates a slot from wall clock times in the given time zone
+func NewSlot(start time.Time, end time.Time, timeZone string) (Slot, error) {
+	location, err := time.LoadLocation(timeZone)
+	if err != nil || timeZone == "" {
+		return Slot{}, fmt.Errorf("unknown time zone: %q", timeZone)
+	}
+
+	slot := Slot{
+		Start:    time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), 0, 0, location),
+		End:      time.Date(end.Year(), end.Month(), end.Day(), end.Hour(), end.Minute(), 0, 0, location),
+		TimeZone: timeZone,
+	}
+	return slot, slot.Validate()
+}
+
+// Validate checks that the slot has a known time zone and ends after it starts
+func (s Slot) Validate() error {
+	if _, err := time.LoadLocation(s.TimeZone); err != nil || s.TimeZone == "" {
+		return fmt.Errorf("unknown time zone: %q", s.TimeZone)
+	}
+	if s.Start.IsZero() || s.End.IsZero() {
+		return errors.New("a slot needs a start and an end")
+	}
+	if !s.End.After(s.Start) {
+		return errors.New("a slot must end after it starts")
+	}
+	return nil
+}
+
+// Local returns the slot's start and end in its own time zone
+func (s Slot) Local() (time.Time, time.Time) {
+	location, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	return s.Start.In(location), s.End.In(location)
+}
+
+// Label describes the slot in its own time zone, eg
+// "Sat 14 Mar 2026, 18:00–21:00 (Europe/London)"
+func (s Slot) Label() string {
+	start, end := s.Local()
+	if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+		return fmt.Sprintf("%s, %s–%s (%s)", start.Format("Mon 2 Jan 2006"), start.Format("15:04"), end.Format("15:04"), s.TimeZone)
+	}
+	return fmt.Sprintf("%s – %s (%s)", start.Format("Mon 2 Jan 2006 15:04"), end.Format("Mon 2 Jan 2006 15:04"), s.TimeZone)
+}
+
+// Label returns what an option is shown as: its text, or for a slot, the
+// slot's date and time followed by any note
+func (o Option) Label() string {
+	if o.Slot == nil {
+		return o.Text
+	}
+	if o.Text == "" {
+		return o.Slot.Label()
+	}
+	return o.Slot.Label() + ": " + o.Text
+}
+
+// ValidateOption checks that an option suits the question: the options of an
+// availability question are slots, with text as an optional note, and other
+// options are text alone
+func (q Question) ValidateOption(option Option) error {
+	if q.Kind() != AvailabilityQuestion {
+		if option.Slot != nil {
+			return errors.New("only availability questions have slots for options")
+		}
+		if option.Text == "" {
+			return errors.New("an option needs text")
+		}
+		return nil
+	}
+
+	if option.Slot == nil {
+		return errors.New("options of an availability question need a slot")
+	}
+	return option.Slot.Validate()
+}
+
+// SlotScore is how well a slot of an availability question suits everyone
+type SlotScore struct {
+	Option Option  `json:"option"`
+	Yes    int     `json:"yes"`
+	Maybe  int     `json:"maybe"`
+	No     int     `json:"no"`
+	Score  float64 `json:"score"` // Every yes counts 1 and every maybe counts maybeCredit
+}
+
+// AvailabilityMatrix is everyone's answers for every slot of an availability
+// question
+type AvailabilityMatrix struct {
+	Slots []Option // In chronological order
+	Rows  []AvailabilityRow
+}
+
+// AvailabilityRow is one participant's answers, in the order of the matrix's slots
+type AvailabilityRow struct {
+	Name    string
+	Answers []Availability // Empty where the participant gave no answer
+}
+
+// AvailabilityMatrix returns the answers of every participant who has
+// answered, in the order they first answered
+func (q Question) AvailabilityMatrix() AvailabilityMatrix {
+	matrix := AvailabilityMatrix{Slots: q.slotsInOrder()}
+
+	column := make(map[string]int, len(matrix.Slots))
+	for i, slot := range matrix.Slots {
+		column[slot.ID] = i
+	}
+
+	for _, ballots := range q.ballotsByParticipant() {
+		row := AvailabilityRow{
+			Name:    NewParticipant(ballots[0].ParticipantID, ballots[0].ParticipantName).DisplayName(),
+			Answers: make([]Availability, len(matrix.Slots)),
+		}
+		for _, ballot := range ballots {
+			if i, ok := column[ballot.OptionID]; ok {
+				row.Answers[i] = ballot.Availability
+			}
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	return matrix
+}
+
+// availabilityTallier ranks the slots of an availability question by score.
+// The best scoring slots win; slots scoring the same are ranked
+// chronologically, and all win if they share the best score.
+type availabilityTallier struct{}
+
+func (availabilityTallier) Tally(q Question) Result {
+	scores := make(map[string]*SlotScore, len(q.Options))
+	var ranking []SlotScore
+	for _, option := range q.slotsInOrder() {
+		ranking = append(ranking, SlotScore{Option: option})
+	}
+	for i := range ranking {
+		scores[ranking[i].Option.ID] = &ranking[i]
+	}
+
+	for _, ballot := range q.Ballots {
+		score, ok := scores[ballot.OptionID]
+		if !ok {
+			continue
+		}
+		switch ballot.Availability {
+		case AvailableYes:
+			score.Yes++
+			score.Score++
+		case AvailableMaybe:
+			score.Maybe++
+			score.Score += maybeCredit
+		case AvailableNo:
+			score.No++
+		}
+	}
+
+	// Stable, so equal scores stay in chronological order
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].Score > ranking[j].Score
+	})
+
+	result := Result{Ranking: ranking}
+	for _, score := range ranking {
+		if score.Score == 0 || score.Score < ranking[0].Score {
+			break
+		}
+		result.Winners = append(result.Winners, score.Option)
+	}
+	return result
+}
+
+// slotsInOrder returns the question's options sorted by the start of their
+// slots, with options without a slot last
+func (q Question) slotsInOrder() []Option {
+	options := append([]Option(nil), q.Options...)
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i].Slot, options[j].Slot
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Start.Before(b.Start)
+	})
+	return options
+}
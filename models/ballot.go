@@ -0,0 +1,186 @@
+package models
+
+import "fmt"
+
+// Participant is an anonymous person taking part in events. The ID is kept in
+// a cookie on their device; the display name is optional.
+type Participant struct {
+	DynamoItem
+	ID         string     `json:"-" dynamodbav:"id"`
+	Name       string     `json:"name,omitempty" form:"name" dynamodbav:"name"`
+	EntityType EntityType `json:"-" dynamodbav:"entity_type"`
+}
+
+// NewParticipant creates a new Participant with the proper PK/SK pattern
+func NewParticipant(id string, name string) Participant {
+	return Participant{
+		DynamoItem: DynamoItem{
+			PK: string(ParticipantEntity) + "#" + id,
+			SK: string(ParticipantEntity) + "#" + id,
+		},
+		ID:         id,
+		Name:       name,
+		EntityType: ParticipantEntity,
+	}
+}
+
+// DisplayName returns the participant's name, or a placeholder if they have not given one
+func (p Participant) DisplayName() string {
+	if p.Name == "" {
+		return "Anonymous"
+	}
+	return p.Name
+}
+
+// Ballot records that a participant voted for an option. A participant's
+// ballots for a question are always replaced together. On ranked
+// questions every ballot carries its rank, 1 for the favourite, on
+// availability questions the participant's answer for the slot, and on score
+// questions the score the participant gave the option.
+type Ballot struct {
+	DynamoItem
+	QuestionID      string       `json:"question_id" dynamodbav:"question_id"`
+	OptionID        string       `json:"option_id" dynamodbav:"option_id"`
+	ParticipantID   string       `json:"-" dynamodbav:"participant_id"` // Doubles as the participant's credential
+	ParticipantName string       `json:"participant_name,omitempty" dynamodbav:"participant_name"`
+	Rank            int          `json:"rank,omitempty" dynamodbav:"rank,omitempty"`
+	Availability    Availability `json:"availability,omitempty" dynamodbav:"availability,omitempty"`
+	Score           int          `json:"score,omitempty" dynamodbav:"score,omitempty"`
+	EntityType      EntityType   `json:"-" dynamodbav:"entity_type"`
+}
+
+// NewBallot creates a new Ballot with the proper PK/SK pattern. All ballots of
+// one participant on one question share a partition key.
+func NewBallot(questionID string, participant Participant, optionID string) Ballot {
+	return Ballot{
+		DynamoItem: DynamoItem{
+			PK: BallotPartition(questionID, participant.ID),
+			SK: string(OptionEntity) + "#" + optionID,
+		},
+		QuestionID:      questionID,
+		OptionID:        optionID,
+		ParticipantID:   participant.ID,
+		ParticipantName: participant.Name,
+		EntityType:      BallotEntity,
+	}
+}
+
+// BallotPartition returns the partition key shared by a participant's ballots on a question
+func BallotPartition(questionID string, participantID string) string {
+	return string(BallotEntity) + "#" + questionID + "#" + participantID
+}
+
+// CountVotes sets the Votes of every option to the number of ballots cast for
+// it. An availability answer of "no" is not a vote.
+func CountVotes(options []Option, ballots []Ballot) {
+	counts := make(map[string]int, len(options))
+	for _, ballot := range ballots {
+		if ballot.Availability != AvailableNo {
+			counts[ballot.OptionID]++
+		}
+	}
+	for i := range options {
+		options[i].Votes = counts[options[i].ID]
+	}
+}
+
+// MaxVotes returns how many options one participant may vote for
+func (q Question) MaxVotes() int {
+	switch q.Kind() {
+	case RankedChoiceQuestion, SchulzeQuestion, AvailabilityQuestion, ScoreQuestion:
+		return len(q.Options)
+	case ApprovalQuestion:
+		if q.MaxSelections > 0 && q.MaxSelections < len(q.Options) {
+			return q.MaxSelections
+		}
+		return len(q.Options)
+	default:
+		return 1
+	}
+}
+
+// ValidateBallots checks that a participant's ballots for the question are
+// allowed: every option belongs to the question and has been approved, no
+// option is voted for twice, and the participant does not vote for more
+// options than allowed. On ranked questions the ranks must run from 1 without gaps, and
+// other questions take no ranks. Likewise only availability questions take,
+// and require, an availability answer, and only score questions take a score,
+// which must be on the question's scale.
+func (q Question) ValidateBallots(ballots []Ballot) error {
+	options := make(map[string]bool, len(q.Options))
+	for _, opt := range q.Options {
+		options[opt.ID] = !opt.Pending
+	}
+
+	seen := make(map[string]bool, len(ballots))
+	for _, ballot := range ballots {
+		approved, ok := options[ballot.OptionID]
+		if ballot.QuestionID != q.ID || !ok {
+			return fmt.Errorf("option %s does not belong to question %s", ballot.OptionID, q.ID)
+		}
+		if !approved {
+			return fmt.Errorf("option %s is awaiting the organizer's approval", ballot.OptionID)
+		}
+		if seen[ballot.OptionID] {
+			return fmt.Errorf("option %s voted for more than once", ballot.OptionID)
+		}
+		seen[ballot.OptionID] = true
+	}
+
+	if len(ballots) > q.MaxVotes() {
+		return fmt.Errorf("at most %d vote(s) allowed per question", q.MaxVotes())
+	}
+
+	ranks := make(map[int]bool, len(ballots))
+	for _, ballot := range ballots {
+		if q.Kind() == AvailabilityQuestion && !ballot.Availability.Valid() {
+			return fmt.Errorf("option %s needs an answer of yes, maybe or no", ballot.OptionID)
+		}
+		if q.Kind() != AvailabilityQuestion && ballot.Availability != "" {
+			return fmt.Errorf("options of a %s question take no availability", q.Kind())
+		}
+		if q.Kind() == ScoreQuestion && (ballot.Score < q.ScaleMin || ballot.Score > q.ScaleMax) {
+			return fmt.Errorf("option %s needs a score from %d to %d", ballot.OptionID, q.ScaleMin, q.ScaleMax)
+		}
+		if q.Kind() != ScoreQuestion && ballot.Score != 0 {
+			return fmt.Errorf("options of a %s question cannot be scored", q.Kind())
+		}
+
+		if !q.Kind().IsRanked() {
+			if ballot.Rank != 0 {
+				return fmt.Errorf("options of a %s question cannot be ranked", q.Kind())
+			}
+			continue
+		}
+		if ballot.Rank < 1 || ballot.Rank > len(ballots) || ranks[ballot.Rank] {
+			return fmt.Errorf("ranks must run from 1 to %d, each used once", len(ballots))
+		}
+		ranks[ballot.Rank] = true
+	}
+
+	return nil
+}
+
+// BallotsFor returns the ballots cast on the question by one participant,
+// sorted by rank
+func (q Question) BallotsFor(participantID string) []Ballot {
+	var ballots []Ballot
+	for _, ballot := range q.Ballots {
+		if ballot.ParticipantID == participantID {
+			ballots = append(ballots, ballot)
+		}
+	}
+	sortByRank(ballots)
+	return ballots
+}
+
+// Voters returns the display names of everyone who voted for an option
+func (q Question) Voters(optionID string) []string {
+	var names []string
+	for _, ballot := range q.Ballots {
+		if ballot.OptionID == optionID && ballot.Availability != AvailableNo {
+			names = append(names, NewParticipant(ballot.ParticipantID, ballot.ParticipantName).DisplayName())
+		}
+	}
+	return names
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Decision is the result of a question frozen when voting on it closed
+type Decision struct {
+	Winners   []Option  `json:"winners" dynamodbav:"winners"` // As they were at closing, empty if nobody voted
+	DecidedAt time.Time `json:"decided_at" dynamodbav:"decided_at"`
+	Tie       *Tie      `json:"tie,omitempty" dynamodbav:"tie,omitempty"`             // How a tie for the win was settled, as the question's policy said
+	Shortfall int       `json:"shortfall,omitempty" dynamodbav:"shortfall,omitempty"` // Votes the leading option was short of the question's quorum or majority, leaving no winners
+}
+
+// Decided reports whether voting on the question has closed and its result
+// is frozen
+func (q Question) Decided() bool {
+	return q.Decision != nil
+}
+
+// Deadline returns when voting on the question closes: the earlier of its own
+// close time and its event's, or nil when neither has one. event may be nil.
+func (q Question) Deadline(event *Event) *time.Time {
+	var eventClosesAt *time.Time
+	if event != nil {
+		eventClosesAt = event.ClosesAt
+	}
+
+	switch {
+	case q.ClosesAt == nil:
+		return eventClosesAt
+	case eventClosesAt == nil || q.ClosesAt.Before(*eventClosesAt):
+		return q.ClosesAt
+	default:
+		return eventClosesAt
+	}
+}
+
+// DueToClose reports whether the question is still open although its
+// deadline has passed
+func (q Question) DueToClose(event *Event, now time.Time) bool {
+	deadline := q.Deadline(event)
+	return !q.Decided() && deadline != nil && !now.Before(*deadline)
+}
+
+// Decide freezes the question's current result
+func (q Question) Decide(now time.Time) Decision {
+	result := q.Result()
+	winners := result.Winners
+	if winners == nil {
+		winners = []Option{}
+	}
+	decision := Decision{Winners: winners, DecidedAt: now.UTC(), Tie: result.Tie}
+	if result.Threshold != nil {
+		decision.Shortfall = result.Threshold.VotesNeeded
+	}
+	return decision
+}
@@ -1,26 +1,39 @@
 package models
 
+import (
+	"crypto/subtle"
+	"time"
+)
+
 type EntityType string
 
 const (
 	EventEntity    EntityType = "EVENT"
 	QuestionEntity EntityType = "QUESTION"
 	OptionEntity   EntityType = "OPTION"
+
+	ParticipantEntity EntityType = "PARTICIPANT"
+	BallotEntity      EntityType = "BALLOT"
+	VetoEntity        EntityType = "VETO"
 )
 
 // DynamoItem is the base structure for all items in the single DynamoDB table
 type DynamoItem struct {
-	PK string `json:"pk" dynamodbav:"pk"`
-	SK string `json:"sk" dynamodbav:"sk"`
+	PK string `json:"-" dynamodbav:"pk"` // Storage keys are not part of the API
+	SK string `json:"-" dynamodbav:"sk"`
 }
 
 // Event represents a planning event
 type Event struct {
 	DynamoItem
-	ID         string     `json:"id" dynamodbav:"id"`
-	Name       string     `json:"name" form:"name" binding:"required" dynamodbav:"name"`
-	Questions  []Question `json:"questions,omitempty" dynamodbav:"-"` // Not stored directly in the item
-	EntityType EntityType `json:"-" dynamodbav:"entity_type"`
+	ID              string     `json:"id" dynamodbav:"id"`
+	Name            string     `json:"name" form:"name" binding:"required" dynamodbav:"name"`
+	AdminToken      string     `json:"-" dynamodbav:"admin_token"`                                                                 // Secret that lets the organizer edit and delete
+	ModerateOptions bool       `json:"moderate_options,omitempty" form:"moderate_options" dynamodbav:"moderate_options,omitempty"` // New options wait for the organizer's approval
+	ClosesAt        *time.Time `json:"closes_at,omitempty" form:"-" dynamodbav:"closes_at,omitempty"`                              // Voting on every question closes then, if not before
+	Version         int        `json:"version" form:"-" dynamodbav:"version"`                                                      // Goes up by one with every change
+	Questions       []Question `json:"questions,omitempty" dynamodbav:"-"`                                                         // Not stored directly in the item
+	EntityType      EntityType `json:"-" dynamodbav:"entity_type"`
 }
 
 // NewEvent creates a new Event with the proper PK/SK pattern
@@ -36,14 +49,42 @@ func NewEvent(id string, name string) Event {
 	}
 }
 
+// IsOrganizer reports whether token is the event's admin token. Events
+// created before admin tokens existed have none, and nobody can manage them
+// until an operator issues one with the issue-admin-tokens command.
+func (e Event) IsOrganizer(token string) bool {
+	if e.AdminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(e.AdminToken), []byte(token)) == 1
+}
+
 // Question represents a question within an event
 type Question struct {
 	DynamoItem
-	ID         string     `json:"id" dynamodbav:"id"`
-	EventID    string     `json:"event_id" dynamodbav:"event_id"`
-	Text       string     `json:"text" form:"text" binding:"required" dynamodbav:"text"`
-	Options    []Option   `json:"options,omitempty" dynamodbav:"-"` // Not stored directly in the item
-	EntityType EntityType `json:"-" dynamodbav:"entity_type"`
+	ID              string         `json:"id" dynamodbav:"id"`
+	EventID         string         `json:"event_id" dynamodbav:"event_id"`
+	Text            string         `json:"text" form:"text" binding:"required" dynamodbav:"text"`
+	Type            QuestionType   `json:"type" form:"type" dynamodbav:"type,omitempty"`                                               // Set when the question is created
+	MaxSelections   int            `json:"max_selections,omitempty" form:"max_selections" dynamodbav:"max_selections,omitempty"`       // Approval questions only, 0 for no limit
+	ScaleMin        int            `json:"scale_min,omitempty" form:"scale_min" dynamodbav:"scale_min,omitempty"`                      // Score questions only, set when the question is created
+	ScaleMax        int            `json:"scale_max,omitempty" form:"scale_max" dynamodbav:"scale_max,omitempty"`                      // Score questions only, set when the question is created
+	WinBy           ScoreStatistic `json:"win_by,omitempty" form:"win_by" dynamodbav:"win_by,omitempty"`                               // Score questions only
+	TieBreak        TieBreak       `json:"tie_break,omitempty" form:"tie_break" dynamodbav:"tie_break,omitempty"`                      // How a tie for the win is settled, kept if empty
+	MinParticipants int            `json:"min_participants,omitempty" form:"min_participants" dynamodbav:"min_participants,omitempty"` // No winner until this many have voted, 0 for no quorum
+	Majority        Majority       `json:"majority,omitempty" form:"majority" dynamodbav:"majority,omitempty"`                         // Support the leading option needs, the most votes if empty
+	MajorityPercent int            `json:"majority_percent,omitempty" form:"majority_percent" dynamodbav:"majority_percent,omitempty"` // Supermajorities only
+	VetoLimit       int            `json:"veto_limit,omitempty" form:"veto_limit" dynamodbav:"veto_limit,omitempty"`                   // Vetoes that rule an option out, 0 if the question takes none
+	HideResults     bool           `json:"hide_results,omitempty" form:"hide_results" dynamodbav:"hide_results,omitempty"`             // Keep vote counts and winners secret until voting closes
+	ResultsRevealed bool           `json:"results_revealed,omitempty" form:"-" dynamodbav:"results_revealed,omitempty"`                // Set when the organizer reveals hidden results early
+	Locked          bool           `json:"locked,omitempty" form:"locked" dynamodbav:"locked,omitempty"`                               // Only the organizer can add options
+	ClosesAt        *time.Time     `json:"closes_at,omitempty" form:"-" dynamodbav:"closes_at,omitempty"`                              // Voting closes then, or when the event's does if earlier
+	Decision        *Decision      `json:"decision,omitempty" dynamodbav:"decision,omitempty"`                                         // Set once voting has closed
+	Version         int            `json:"version" form:"-" dynamodbav:"version"`                                                      // Goes up by one with every change
+	Options         []Option       `json:"options,omitempty" dynamodbav:"-"`                                                           // Not stored directly in the item
+	Ballots         []Ballot       `json:"-" dynamodbav:"-"`                                                                           // Not stored directly in the item
+	Vetoes          []Veto         `json:"-" dynamodbav:"-"`                                                                           // Not stored directly in the item
+	EntityType      EntityType     `json:"-" dynamodbav:"entity_type"`
 }
 
 // NewQuestion creates a new Question with the proper PK/SK pattern
@@ -60,36 +101,15 @@ func NewQuestion(id string, eventID string, text string) Question {
 	}
 }
 
+// WinningOptions returns the options that won the question, more than one
+// for a tie, or nil before anyone voted or while the leading options fall
+// short of the question's quorum or majority. Vetoed options never win. Once
+// the question is decided they are the frozen winners.
 func (q Question) WinningOptions() []Option {
-	if len(q.Options) == 0 {
-		return nil
+	if q.Decided() {
+		return q.Decision.Winners
 	}
-
-	allZero := true
-	maxVotes := q.Options[0].Votes
-
-	for _, opt := range q.Options {
-		if opt.Votes > 0 {
-			allZero = false
-		}
-		if opt.Votes > maxVotes {
-			maxVotes = opt.Votes
-		}
-	}
-
-	if allZero {
-		return nil
-	}
-
-	// Collect all options with max votes
-	var winners []Option
-	for _, opt := range q.Options {
-		if opt.Votes == maxVotes {
-			winners = append(winners, opt)
-		}
-	}
-
-	return winners
+	return q.Result().Winners
 }
 
 // Option represents an answer option for a question
@@ -98,7 +118,12 @@ type Option struct {
 	ID         string     `json:"id" dynamodbav:"id"`
 	QuestionID string     `json:"question_id" dynamodbav:"question_id"`
 	Text       string     `json:"text" form:"text" binding:"required" dynamodbav:"text"`
-	Votes      int        `json:"votes" dynamodbav:"votes"`
+	Slot       *Slot      `json:"slot,omitempty" dynamodbav:"slot,omitempty"`       // Availability questions only
+	Votes      int        `json:"votes" dynamodbav:"-"`                             // Counted from the ballots
+	Vetoes     int        `json:"vetoes,omitempty" dynamodbav:"-"`                  // Counted from the vetoes
+	Pending    bool       `json:"pending,omitempty" dynamodbav:"pending,omitempty"` // Suggested on a moderated event and not yet approved
+	CreatedAt  time.Time  `json:"created_at" dynamodbav:"created_at"`               // Set by the store if left out
+	Version    int        `json:"version" form:"-" dynamodbav:"version"`            // Goes up by one with every change
 	EntityType EntityType `json:"-" dynamodbav:"entity_type"`
 }
 
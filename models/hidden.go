@@ -0,0 +1,55 @@
+package models
+
+// ResultsHidden reports whether the question keeps its vote counts and
+// winners secret: it hides its results, voting on it is still open, and the
+// organizer has not revealed them
+func (q Question) ResultsHidden() bool {
+	return q.HideResults && !q.ResultsRevealed && !q.Decided()
+}
+
+// Redacted returns the question as it may be shown to anyone, without the
+// options awaiting approval. While its results are hidden, its options come
+// without vote or veto counts, and the ballots and vetoes they were counted
+// from are left out.
+func (q Question) Redacted() Question {
+	q = q.withoutPending()
+	if !q.ResultsHidden() {
+		return q
+	}
+
+	options := make([]Option, len(q.Options))
+	for i, option := range q.Options {
+		options[i] = option.Redacted()
+	}
+	q.Options = options
+	q.Ballots, q.Vetoes = nil, nil
+	return q
+}
+
+// Redacted returns the option without its vote and veto counts, for a
+// question whose results are hidden
+func (o Option) Redacted() Option {
+	o.Votes, o.Vetoes = 0, 0
+	return o
+}
+
+// Redacted returns the event with every question redacted, see
+// Question.Redacted
+func (e Event) Redacted() Event {
+	if e.Questions == nil {
+		return e
+	}
+
+	questions := make([]Question, len(e.Questions))
+	for i, question := range e.Questions {
+		questions[i] = question.Redacted()
+	}
+	e.Questions = questions
+	return e
+}
+
+// HiddenResult is what the result of a question looks like while its
+// results are hidden
+func HiddenResult() Result {
+	return Result{Winners: []Option{}, Hidden: true}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// duplicateStopWords are left out when comparing option texts, so "The Thai
+// place" still looks like "Thai place"
+var duplicateStopWords = map[string]bool{
+	"a":   true,
+	"an":  true,
+	"and": true,
+	"at":  true,
+	"of":  true,
+	"the": true,
+}
+
+// availabilityPreference orders the answers of an availability question,
+// from least to most available
+var availabilityPreference = map[Availability]int{
+	AvailableNo:    0,
+	AvailableMaybe: 1,
+	AvailableYes:   2,
+}
+
+// Duplicates returns the approved options of the question that look like
+// the same choice as option: the same slot on an availability question,
+// otherwise text whose words, ignoring case, punctuation and filler such as
+// "the", are all among the other's. "Thai" and "thai food" are duplicates;
+// "Thai food" and "Thai restaurant" are not.
+func (q Question) Duplicates(option Option) []Option {
+	var duplicates []Option
+	for _, existing := range q.withoutPending().Options {
+		if existing.ID == option.ID {
+			continue
+		}
+		if existing.Slot != nil || option.Slot != nil {
+			if existing.Slot != nil && option.Slot != nil &&
+				existing.Slot.Start.Equal(option.Slot.Start) && existing.Slot.End.Equal(option.Slot.End) {
+				duplicates = append(duplicates, existing)
+			}
+			continue
+		}
+		if sameChoice(existing.Text, option.Text) {
+			duplicates = append(duplicates, existing)
+		}
+	}
+	return duplicates
+}
+
+// ValidateMerge checks that the option can be merged into another option of
+// the same question
+func (o Option) ValidateMerge(into Option) error {
+	if into.QuestionID != o.QuestionID {
+		return fmt.Errorf("option %s does not belong to question %s", into.ID, o.QuestionID)
+	}
+	if into.ID == o.ID {
+		return fmt.Errorf("option %s cannot be merged into itself", o.ID)
+	}
+	return nil
+}
+
+// MergedBallots returns the ballots of everyone who voted for the option
+// from, by participant ID, with that vote moved to the option into. Someone
+// who voted for both keeps a single ballot for into, the more favourable of
+// the two: the higher rank, the better availability or the higher score.
+// Rankings are closed up again afterwards.
+func (q Question) MergedBallots(from string, into string) map[string][]Ballot {
+	merged := make(map[string][]Ballot)
+	for _, ballot := range q.Ballots {
+		if ballot.OptionID != from {
+			continue
+		}
+
+		participant := NewParticipant(ballot.ParticipantID, ballot.ParticipantName)
+		kept := ballot
+		var ballots []Ballot
+		for _, other := range q.BallotsFor(participant.ID) {
+			switch other.OptionID {
+			case from:
+			case into:
+				if !q.preferred(kept, other) {
+					kept = other
+				}
+			default:
+				ballots = append(ballots, other)
+			}
+		}
+
+		moved := NewBallot(q.ID, participant, into)
+		moved.Rank, moved.Availability, moved.Score = kept.Rank, kept.Availability, kept.Score
+		ballots = append(ballots, moved)
+		if q.Kind().IsRanked() {
+			sortByRank(ballots)
+			for i := range ballots {
+				ballots[i].Rank = i + 1
+			}
+		}
+		merged[participant.ID] = ballots
+	}
+	return merged
+}
+
+// MergedVetoes returns the vetoes of everyone who vetoed the option from, by
+// participant ID, with that veto moved to the option into. Someone who
+// vetoed both keeps a single veto of into.
+func (q Question) MergedVetoes(from string, into string) map[string][]Veto {
+	merged := make(map[string][]Veto)
+	for _, veto := range q.Vetoes {
+		if veto.OptionID != from {
+			continue
+		}
+
+		participant := NewParticipant(veto.ParticipantID, veto.ParticipantName)
+		vetoes := []Veto{NewVeto(q.ID, participant, into)}
+		for _, other := range q.VetoesFor(participant.ID) {
+			if other.OptionID != from && other.OptionID != into {
+				vetoes = append(vetoes, other)
+			}
+		}
+		merged[participant.ID] = vetoes
+	}
+	return merged
+}
+
+// preferred reports whether a participant's ballot shows them at least as
+// keen on its option as the other ballot does on its own
+func (q Question) preferred(ballot Ballot, other Ballot) bool {
+	switch {
+	case q.Kind().IsRanked():
+		return ballot.Rank <= other.Rank
+	case q.Kind() == AvailabilityQuestion:
+		return availabilityPreference[ballot.Availability] >= availabilityPreference[other.Availability]
+	case q.Kind() == ScoreQuestion:
+		return ballot.Score >= other.Score
+	default:
+		return true
+	}
+}
+
+// sameChoice reports whether every word of one text is among the words of
+// the other
+func sameChoice(text string, other string) bool {
+	words, otherWords := choiceWords(text), choiceWords(other)
+	if len(words) == 0 || len(otherWords) == 0 {
+		return false
+	}
+	if len(words) > len(otherWords) {
+		words, otherWords = otherWords, words
+	}
+
+	for word := range words {
+		if !otherWords[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// choiceWords returns the lower-cased words of an option's text, leaving out
+// punctuation and stop words
+func choiceWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !duplicateStopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
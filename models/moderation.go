@@ -0,0 +1,29 @@
+package models
+
+// PendingOptions returns the options suggested on a moderated event that the
+// organizer has not approved yet
+func (q Question) PendingOptions() []Option {
+	var pending []Option
+	for _, option := range q.Options {
+		if option.Pending {
+			pending = append(pending, option)
+		}
+	}
+	return pending
+}
+
+// withoutPending returns the question with only its approved options
+func (q Question) withoutPending() Question {
+	if q.PendingOptions() == nil {
+		return q
+	}
+
+	options := make([]Option, 0, len(q.Options))
+	for _, option := range q.Options {
+		if !option.Pending {
+			options = append(options, option)
+		}
+	}
+	q.Options = options
+	return q
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/evoteum/planzoco/go/planzoco/tally"
+)
+
+const (
+	// DefaultScaleMax is the top of a score question's scale when none is
+	// given, with the scale starting at 0
+	DefaultScaleMax = 5
+	// maxScaleSteps keeps a scale short enough to offer every score in a list
+	maxScaleSteps = 100
+)
+
+// ScoreStatistic decides which options of a score question win
+type ScoreStatistic string
+
+const (
+	// ByMean makes the options with the highest average score win
+	ByMean ScoreStatistic = "mean"
+	// ByMedian makes the options with the highest median score win, so a few
+	// extreme scores sway the result less
+	ByMedian ScoreStatistic = "median"
+)
+
+// ScoreStatistics lists every statistic, in the order they are offered
+var ScoreStatistics = []ScoreStatistic{ByMean, ByMedian}
+
+// Valid reports whether s is a known statistic
+func (s ScoreStatistic) Valid() bool {
+	return s == ByMean || s == ByMedian
+}
+
+// Label returns the name of the statistic shown to people
+func (s ScoreStatistic) Label() string {
+	if s == ByMedian {
+		return "Highest median"
+	}
+	return "Highest average"
+}
+
+// Statistic returns how the question's winners are picked if it is a score
+// question. Questions without one are won by the mean.
+func (q Question) Statistic() ScoreStatistic {
+	if q.WinBy == "" {
+		return ByMean
+	}
+	return q.WinBy
+}
+
+// ScaleScores returns every score on the question's scale, lowest first
+func (q Question) ScaleScores() []int {
+	scores := make([]int, 0, q.ScaleMax-q.ScaleMin+1)
+	for score := q.ScaleMin; score <= q.ScaleMax; score++ {
+		scores = append(scores, score)
+	}
+	return scores
+}
+
+// validateScale checks the settings of a score question
+func (q Question) validateScale() error {
+	if q.ScaleMax <= q.ScaleMin {
+		return errors.New("the top of the scale must be above the bottom")
+	}
+	if q.ScaleMax-q.ScaleMin > maxScaleSteps {
+		return fmt.Errorf("a scale can have at most %d steps", maxScaleSteps)
+	}
+	if q.WinBy != "" && !q.WinBy.Valid() {
+		return fmt.Errorf("unknown statistic: %s", q.WinBy)
+	}
+	return nil
+}
+
+// OptionScore is how an option of a score question was rated
+type OptionScore struct {
+	Option       Option       `json:"option"`
+	Ratings      int          `json:"ratings"`
+	Mean         float64      `json:"mean"`
+	Median       float64      `json:"median"`
+	Distribution []ScoreCount `json:"distribution"` // Every score on the scale, lowest first
+}
+
+// Statistic returns the option's mean or median
+func (s OptionScore) Statistic(statistic ScoreStatistic) float64 {
+	if statistic == ByMedian {
+		return s.Median
+	}
+	return s.Mean
+}
+
+// ScoreCount is how many participants gave an option one score
+type ScoreCount struct {
+	Score   int `json:"score"`
+	Count   int `json:"count"`
+	Percent int `json:"percent"` // Share of the option's ratings, rounded down
+}
+
+// scoreTallier rates the options of a score question, and makes the rated
+// options with the best mean or median, as the question chooses, the winners
+type scoreTallier struct{}
+
+func (scoreTallier) Tally(q Question) Result {
+	ratings := make(map[string][]int, len(q.Options))
+	for _, ballot := range q.Ballots {
+		ratings[ballot.OptionID] = append(ratings[ballot.OptionID], ballot.Score)
+	}
+
+	var result Result
+	for _, option := range q.Options {
+		scores := ratings[option.ID]
+		score := OptionScore{
+			Option:  option,
+			Ratings: len(scores),
+			Mean:    tally.Mean(scores),
+			Median:  tally.Median(scores),
+		}
+
+		counts := make(map[int]int, len(scores))
+		for _, s := range scores {
+			counts[s]++
+		}
+		for _, s := range q.ScaleScores() {
+			count := ScoreCount{Score: s, Count: counts[s]}
+			if len(scores) > 0 {
+				count.Percent = counts[s] * 100 / len(scores)
+			}
+			score.Distribution = append(score.Distribution, count)
+		}
+
+		result.Scores = append(result.Scores, score)
+	}
+
+	statistic := q.Statistic()
+	var best float64
+	for _, score := range result.Scores {
+		if score.Ratings == 0 {
+			continue
+		}
+		switch {
+		case result.Winners == nil || score.Statistic(statistic) > best:
+			best = score.Statistic(statistic)
+			result.Winners = []Option{score.Option}
+		case score.Statistic(statistic) == best:
+			result.Winners = append(result.Winners, score.Option)
+		}
+	}
+
+	return result
+}
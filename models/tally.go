@@ -0,0 +1,345 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/evoteum/planzoco/go/planzoco/tally"
+)
+
+// QuestionType decides how a question is voted on and how its winner is found
+type QuestionType string
+
+const (
+	// PluralityQuestion lets every participant vote for one option, and the
+	// options with the most votes win
+	PluralityQuestion QuestionType = "plurality"
+	// RankedChoiceQuestion lets every participant rank the options, and the
+	// winner is found by instant-runoff
+	RankedChoiceQuestion QuestionType = "ranked"
+	// SchulzeQuestion takes the same ballots as RankedChoiceQuestion, and the
+	// winner is found by the Schulze method, comparing every pair of options
+	SchulzeQuestion QuestionType = "schulze"
+	// ApprovalQuestion lets every participant pick all the options they are
+	// happy with, up to the question's MaxSelections, and the options with
+	// the most approvals win
+	ApprovalQuestion QuestionType = "approval"
+	// AvailabilityQuestion has date and time slots for options, which every
+	// participant marks yes, maybe or no. Slots are ranked by their score.
+	AvailabilityQuestion QuestionType = "availability"
+	// ScoreQuestion lets every participant rate every option on the
+	// question's scale, and the options with the best mean or median win
+	ScoreQuestion QuestionType = "score"
+)
+
+// QuestionTypes lists every question type, in the order they are offered
+var QuestionTypes = []QuestionType{PluralityQuestion, ApprovalQuestion, RankedChoiceQuestion, SchulzeQuestion, ScoreQuestion, AvailabilityQuestion}
+
+// Valid reports whether t is a known question type
+func (t QuestionType) Valid() bool {
+	_, ok := talliers[t]
+	return ok
+}
+
+// Label returns the name of the question type shown to people
+func (t QuestionType) Label() string {
+	switch t {
+	case RankedChoiceQuestion:
+		return "Ranked choice"
+	case SchulzeQuestion:
+		return "Ranked pairwise (Schulze)"
+	case ApprovalQuestion:
+		return "Approval"
+	case AvailabilityQuestion:
+		return "Availability"
+	case ScoreQuestion:
+		return "Score"
+	default:
+		return "Single choice"
+	}
+}
+
+// IsRanked reports whether participants rank the options of questions of this type
+func (t QuestionType) IsRanked() bool {
+	return t == RankedChoiceQuestion || t == SchulzeQuestion
+}
+
+// Kind returns the question's type. Questions created before there were
+// types have none, and are plurality questions.
+func (q Question) Kind() QuestionType {
+	if q.Type == "" {
+		return PluralityQuestion
+	}
+	return q.Type
+}
+
+// WithDefaults returns the question with the settings it leaves out filled in
+// for its type: plurality for a question without a type, and a scale from 0
+// to DefaultScaleMax won by the mean for a score question
+func (q Question) WithDefaults() Question {
+	if q.Type == "" {
+		q.Type = PluralityQuestion
+	}
+	if q.TieBreak == "" {
+		q.TieBreak = KeepTie
+	}
+	if q.Majority == "" {
+		q.Majority = MostVotes
+	}
+	if q.Majority == Supermajority && q.MajorityPercent == 0 {
+		q.MajorityPercent = DefaultMajorityPercent
+	}
+	if q.Kind() == ScoreQuestion {
+		if q.ScaleMin == 0 && q.ScaleMax == 0 {
+			q.ScaleMax = DefaultScaleMax
+		}
+		if q.WinBy == "" {
+			q.WinBy = ByMean
+		}
+	}
+	return q
+}
+
+// ValidateSettings checks that the question has a known type, and only the
+// settings that type uses
+func (q Question) ValidateSettings() error {
+	if !q.Kind().Valid() {
+		return fmt.Errorf("unknown question type: %s", q.Type)
+	}
+	if !q.TieBreakPolicy().Valid() {
+		return fmt.Errorf("unknown tie-break policy: %s", q.TieBreak)
+	}
+	if err := q.validateThreshold(); err != nil {
+		return err
+	}
+	if q.VetoLimit < 0 {
+		return errors.New("the number of vetoes that rule an option out cannot be negative")
+	}
+	if q.MaxSelections < 0 {
+		return errors.New("the maximum number of selections cannot be negative")
+	}
+	if q.MaxSelections != 0 && q.Kind() != ApprovalQuestion {
+		return errors.New("only approval questions have a maximum number of selections")
+	}
+	if q.Kind() == ScoreQuestion {
+		return q.validateScale()
+	}
+	if q.ScaleMin != 0 || q.ScaleMax != 0 || q.WinBy != "" {
+		return errors.New("only score questions have a scale")
+	}
+	return nil
+}
+
+// Result is the outcome of counting a question's ballots
+type Result struct {
+	Winners   []Option       `json:"winners"`             // More than one only for a tie, nil before anyone voted or while short of the question's rules
+	Rounds    []RunoffRound  `json:"rounds,omitempty"`    // How an instant-runoff count went, nil for other types
+	Ranking   []SlotScore    `json:"ranking,omitempty"`   // Slots from best to worst, nil for other types
+	Scores    []OptionScore  `json:"scores,omitempty"`    // Ratings of every option of a score question, nil for other types
+	Pairwise  *PairwiseTable `json:"pairwise,omitempty"`  // How a Schulze count compared the options, nil for other types
+	Tie       *Tie           `json:"tie,omitempty"`       // How a tie for the win was settled, nil without one
+	Threshold *Threshold     `json:"threshold,omitempty"` // How the leading options measure up to the question's quorum and majority, nil without either
+	Hidden    bool           `json:"hidden,omitempty"`    // Set instead of everything else while the question's results are hidden
+}
+
+// RunoffRound is one round of an instant-runoff count
+type RunoffRound struct {
+	Number     int           `json:"number"`
+	Votes      []OptionVotes `json:"votes"`                // Options still in the count, in option order
+	Exhausted  int           `json:"exhausted"`            // Ballots ranking none of the options still in the count
+	Eliminated *Option       `json:"eliminated,omitempty"` // Dropped after this round, nil in the final round
+}
+
+// OptionVotes is the number of votes an option holds in a runoff round
+type OptionVotes struct {
+	Option Option `json:"option"`
+	Votes  int    `json:"votes"`
+}
+
+// PairwiseTable is how a Schulze count compared every pair of options. The
+// tables are indexed in the order of Options.
+type PairwiseTable struct {
+	Options []Option `json:"options"`
+	// Preferences[i][j] is the number of participants ranking Options[i]
+	// above Options[j]
+	Preferences [][]int `json:"preferences"`
+	// StrongestPaths[i][j] is the strength of the strongest beatpath from
+	// Options[i] to Options[j]
+	StrongestPaths [][]int `json:"strongest_paths"`
+}
+
+// Beats reports whether more participants prefer Options[i] to Options[j]
+// than the other way round
+func (t PairwiseTable) Beats(i int, j int) bool {
+	return t.Preferences[i][j] > t.Preferences[j][i]
+}
+
+// BeatsByPath reports whether the strongest path from Options[i] to
+// Options[j] is stronger than the one back
+func (t PairwiseTable) BeatsByPath(i int, j int) bool {
+	return t.StrongestPaths[i][j] > t.StrongestPaths[j][i]
+}
+
+// Tallier counts the ballots of a question into its result. Every question
+// type has one.
+type Tallier interface {
+	Tally(q Question) Result
+}
+
+var talliers = map[QuestionType]Tallier{
+	PluralityQuestion:    mostVotesTallier{},
+	ApprovalQuestion:     mostVotesTallier{},
+	RankedChoiceQuestion: instantRunoffTallier{},
+	SchulzeQuestion:      schulzeTallier{},
+	AvailabilityQuestion: availabilityTallier{},
+	ScoreQuestion:        scoreTallier{},
+}
+
+// Result counts the question's ballots the way its type prescribes,
+// excluding pending and vetoed options. It then withholds winners until they
+// meet the question's quorum and majority, and settles ties for the win by
+// the question's tie-break policy.
+func (q Question) Result() Result {
+	return q.breakTie(q.applyThreshold(talliers[q.Kind()].Tally(q.withoutPending().withoutVetoed())))
+}
+
+// mostVotesTallier makes the options with the most votes the winners
+type mostVotesTallier struct{}
+
+func (mostVotesTallier) Tally(q Question) Result {
+	if len(q.Options) == 0 {
+		return Result{}
+	}
+
+	allZero := true
+	maxVotes := q.Options[0].Votes
+
+	for _, opt := range q.Options {
+		if opt.Votes > 0 {
+			allZero = false
+		}
+		if opt.Votes > maxVotes {
+			maxVotes = opt.Votes
+		}
+	}
+
+	if allZero {
+		return Result{}
+	}
+
+	// Collect all options with max votes
+	var winners []Option
+	for _, opt := range q.Options {
+		if opt.Votes == maxVotes {
+			winners = append(winners, opt)
+		}
+	}
+
+	return Result{Winners: winners}
+}
+
+// instantRunoffTallier finds the winner of a ranked-choice question with
+// tally.InstantRunoff. The options are handed to it in the order they were
+// created, the way the store returns them, so its last resort tie-break
+// (eliminating the candidate listed latest) drops the tied option added last,
+// just as the earliest tie-break policy keeps the one added first.
+type instantRunoffTallier struct{}
+
+func (instantRunoffTallier) Tally(q Question) Result {
+	byID := make(map[string]Option, len(q.Options))
+	candidates := make([]string, 0, len(q.Options))
+	for _, option := range q.Options {
+		byID[option.ID] = option
+		candidates = append(candidates, option.ID)
+	}
+
+	counted := tally.InstantRunoff(candidates, q.rankings())
+
+	var result Result
+	for _, id := range counted.Winners {
+		result.Winners = append(result.Winners, byID[id])
+	}
+	for i, round := range counted.Rounds {
+		runoffRound := RunoffRound{Number: i + 1, Exhausted: round.Exhausted}
+		for _, option := range q.Options {
+			if votes, ok := round.Votes[option.ID]; ok {
+				runoffRound.Votes = append(runoffRound.Votes, OptionVotes{Option: option, Votes: votes})
+			}
+		}
+		if round.Eliminated != "" {
+			eliminated := byID[round.Eliminated]
+			runoffRound.Eliminated = &eliminated
+		}
+		result.Rounds = append(result.Rounds, runoffRound)
+	}
+
+	return result
+}
+
+// schulzeTallier finds the winners of a ranked question with tally.Schulze,
+// comparing the options in the order the store returned them
+type schulzeTallier struct{}
+
+func (schulzeTallier) Tally(q Question) Result {
+	byID := make(map[string]Option, len(q.Options))
+	candidates := make([]string, 0, len(q.Options))
+	for _, option := range q.Options {
+		byID[option.ID] = option
+		candidates = append(candidates, option.ID)
+	}
+
+	counted := tally.Schulze(candidates, q.rankings())
+
+	result := Result{Pairwise: &PairwiseTable{
+		Options:        q.Options,
+		Preferences:    counted.Preferences,
+		StrongestPaths: counted.StrongestPaths,
+	}}
+	for _, id := range counted.Winners {
+		result.Winners = append(result.Winners, byID[id])
+	}
+	return result
+}
+
+// rankings returns every participant's ballots as a list of option IDs from
+// most to least preferred
+func (q Question) rankings() [][]string {
+	var rankings [][]string
+	for _, ballots := range q.ballotsByParticipant() {
+		ranking := make([]string, 0, len(ballots))
+		for _, ballot := range ballots {
+			ranking = append(ranking, ballot.OptionID)
+		}
+		rankings = append(rankings, ranking)
+	}
+	return rankings
+}
+
+// ballotsByParticipant groups the question's ballots by participant, in the
+// order the participants first voted, with each participant's ballots sorted
+// by rank
+func (q Question) ballotsByParticipant() [][]Ballot {
+	index := make(map[string]int)
+	var grouped [][]Ballot
+	for _, ballot := range q.Ballots {
+		i, ok := index[ballot.ParticipantID]
+		if !ok {
+			i = len(grouped)
+			index[ballot.ParticipantID] = i
+			grouped = append(grouped, nil)
+		}
+		grouped[i] = append(grouped[i], ballot)
+	}
+
+	for _, ballots := range grouped {
+		sortByRank(ballots)
+	}
+	return grouped
+}
+
+func sortByRank(ballots []Ballot) {
+	sort.SliceStable(ballots, func(i, j int) bool {
+		return ballots[i].Rank < ballots[j].Rank
+	})
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+// TestRankedChoiceTieEliminatesLastAdded makes sure a ranked-choice tie for
+// the fewest votes drops the option added last, not the one whose ID sorts
+// last
+func TestRankedChoiceTieEliminatesLastAdded(t *testing.T) {
+	question := NewQuestion("q1", "e1", "Where?")
+	question.Type = RankedChoiceQuestion
+	// In the order they were added, which is not the order of their IDs
+	question.Options = []Option{
+		NewOption("z", "q1", "Thai"),
+		NewOption("a", "q1", "Pizza"),
+		NewOption("m", "q1", "Curry"),
+	}
+
+	ballot := func(participantID string, optionID string, rank int) Ballot {
+		b := NewBallot("q1", NewParticipant(participantID, ""), optionID)
+		b.Rank = rank
+		return b
+	}
+	question.Ballots = []Ballot{
+		ballot("p1", "z", 1),
+		ballot("p2", "a", 1),
+		ballot("p2", "z", 2),
+		ballot("p3", "m", 1),
+		ballot("p4", "m", 1),
+	}
+
+	result := question.Result()
+
+	if len(result.Rounds) == 0 || result.Rounds[0].Eliminated == nil {
+		t.Fatalf("no option was eliminated in the first round: %+v", result.Rounds)
+	}
+	if eliminated := result.Rounds[0].Eliminated.ID; eliminated != "a" {
+		t.Errorf("eliminated %s in the first round, want a", eliminated)
+	}
+	if len(result.Winners) != 2 || result.Winners[0].ID != "z" || result.Winners[1].ID != "m" {
+		t.Errorf("winners = %+v, want z and m tied", result.Winners)
+	}
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Majority is how much support the leading option of a question needs before
+// it wins
+type Majority string
+
+const (
+	// MostVotes lets the leading option win however little support it has
+	MostVotes Majority = "most"
+	// AbsoluteMajority needs the leading option backed by more than half of
+	// everyone who voted on the question
+	AbsoluteMajority Majority = "absolute"
+	// Supermajority needs the leading option backed by at least the
+	// question's MajorityPercent of everyone who voted on it
+	Supermajority Majority = "super"
+)
+
+// DefaultMajorityPercent is the share of voters a supermajority needs when the
+// question doesn't say, two thirds rounded up
+const DefaultMajorityPercent = 67
+
+// Majorities lists every majority rule, in the order they are offered
+var Majorities = []Majority{MostVotes, AbsoluteMajority, Supermajority}
+
+// Valid reports whether m is a known majority rule
+func (m Majority) Valid() bool {
+	return m == MostVotes || m == AbsoluteMajority || m == Supermajority
+}
+
+// Label returns the name of the majority rule shown to people
+func (m Majority) Label() string {
+	switch m {
+	case AbsoluteMajority:
+		return "More than half"
+	case Supermajority:
+		return "Supermajority"
+	default:
+		return "Most votes"
+	}
+}
+
+// Threshold is how the leading option of a question measures up to the
+// question's quorum and majority rules
+type Threshold struct {
+	Participants int  `json:"participants"` // Everyone who voted on the question
+	Support      int  `json:"support"`      // Voters backing the leading option, by what backing means for the question type
+	Met          bool `json:"met"`          // Whether the leading option may win
+	VotesNeeded  int  `json:"votes_needed"` // More votes for the leading option that would meet the rules, 0 once met
+}
+
+// MajorityRule returns how much support the leading option needs to win.
+// Questions without a rule are won by the most votes.
+func (q Question) MajorityRule() Majority {
+	if q.Majority == "" {
+		return MostVotes
+	}
+	return q.Majority
+}
+
+// HasThreshold reports whether the question has a quorum or a majority rule
+func (q Question) HasThreshold() bool {
+	return q.MinParticipants > 0 || q.MajorityRule() != MostVotes
+}
+
+// ThresholdDescription describes the question's quorum and majority rules, eg
+// "At least 5 voters, and the winner backed by more than half of them", or ""
+// without either
+func (q Question) ThresholdDescription() string {
+	var majority string
+	switch q.MajorityRule() {
+	case AbsoluteMajority:
+		majority = "backed by more than half"
+	case Supermajority:
+		majority = fmt.Sprintf("backed by at least %d%%", q.MajorityPercent)
+	}
+
+	switch {
+	case q.MinParticipants > 0 && majority != "":
+		return fmt.Sprintf("At least %d voters, and the winner %s of them", q.MinParticipants, majority)
+	case q.MinParticipants > 0:
+		return fmt.Sprintf("At least %d voters", q.MinParticipants)
+	case majority != "":
+		return fmt.Sprintf("The winner %s of the voters", majority)
+	default:
+		return ""
+	}
+}
+
+// VotesNeeded returns how many more votes the question needs before it has a
+// winner under its quorum and majority rules, or 0 if it needs none. Decided
+// questions need none.
+func (q Question) VotesNeeded() int {
+	if q.Decided() {
+		return 0
+	}
+	if threshold := q.Result().Threshold; threshold != nil {
+		return threshold.VotesNeeded
+	}
+	return 0
+}
+
+// validateThreshold checks the question's quorum and majority rules
+func (q Question) validateThreshold() error {
+	if q.MinParticipants < 0 {
+		return errors.New("the minimum number of participants cannot be negative")
+	}
+	if !q.MajorityRule().Valid() {
+		return fmt.Errorf("unknown majority rule: %s", q.Majority)
+	}
+	if q.MajorityRule() != Supermajority {
+		if q.MajorityPercent != 0 {
+			return errors.New("only a supermajority takes a percentage")
+		}
+		return nil
+	}
+	if q.MajorityPercent <= 50 || q.MajorityPercent > 100 {
+		return errors.New("a supermajority must be more than 50% and at most 100%")
+	}
+	return nil
+}
+
+// applyThreshold measures the result's leading options against the
+// question's quorum and majority rules, and takes away their win if they
+// fall short
+func (q Question) applyThreshold(result Result) Result {
+	if !q.HasThreshold() {
+		return result
+	}
+
+	threshold := &Threshold{Participants: len(q.ballotsByParticipant())}
+	if len(result.Winners) > 0 {
+		// Tied options have the same support
+		threshold.Support = q.support(result, result.Winners[0])
+	}
+	threshold.VotesNeeded = q.votesNeeded(threshold.Participants, threshold.Support)
+	threshold.Met = threshold.VotesNeeded == 0 && len(result.Winners) > 0
+	if !threshold.Met && threshold.VotesNeeded == 0 {
+		threshold.VotesNeeded = 1
+	}
+
+	result.Threshold = threshold
+	if !threshold.Met {
+		result.Winners = nil
+	}
+	return result
+}
+
+// support returns how many voters back the option, which depends on how the
+// question's type counts:
+//
+//   - ranked choice: those left with it in the final round of the runoff
+//   - Schulze: the fewest who prefer it to any one other option, so a
+//     majority of them means it beats every other option head to head
+//   - score: those scoring it above the middle of the scale
+//   - availability: those who said yes to it
+//   - plurality and approval: everyone who voted for it
+func (q Question) support(result Result, option Option) int {
+	switch {
+	case len(result.Rounds) > 0:
+		for _, votes := range result.Rounds[len(result.Rounds)-1].Votes {
+			if votes.Option.ID == option.ID {
+				return votes.Votes
+			}
+		}
+		return 0
+	case result.Pairwise != nil:
+		return result.Pairwise.support(option)
+	case q.Kind() == ScoreQuestion:
+		support := 0
+		for _, ballot := range q.Ballots {
+			if ballot.OptionID == option.ID && 2*ballot.Score > q.ScaleMin+q.ScaleMax {
+				support++
+			}
+		}
+		return support
+	case q.Kind() == AvailabilityQuestion:
+		support := 0
+		for _, ballot := range q.Ballots {
+			if ballot.OptionID == option.ID && ballot.Availability == AvailableYes {
+				support++
+			}
+		}
+		return support
+	}
+	return option.Votes
+}
+
+// support returns the fewest participants preferring the option to any one
+// other option, or everyone who ranked it if it is the only option
+func (t PairwiseTable) support(option Option) int {
+	i := -1
+	for j, compared := range t.Options {
+		if compared.ID == option.ID {
+			i = j
+		}
+	}
+	if i < 0 {
+		return 0
+	}
+
+	support := -1
+	for j := range t.Options {
+		if j != i && (support < 0 || t.Preferences[i][j] < support) {
+			support = t.Preferences[i][j]
+		}
+	}
+	if support < 0 {
+		return option.Votes
+	}
+	return support
+}
+
+// votesNeeded returns how many more voters would have to back the leading
+// option, which support of the participants already do, for it to meet the
+// question's rules
+func (q Question) votesNeeded(participants int, support int) int {
+	needed := 0
+	if q.MinParticipants > participants {
+		needed = q.MinParticipants - participants
+	}
+
+	var majority int
+	switch q.MajorityRule() {
+	case AbsoluteMajority:
+		// support+k > (participants+k)/2
+		majority = participants - 2*support + 1
+	case Supermajority:
+		percent := q.MajorityPercent
+		short := percent*participants - 100*support
+		switch {
+		case short <= 0:
+		case percent == 100:
+			// New votes alone never make it unanimous, so count the voters
+			// who would have to change their minds
+			majority = participants - support
+		default:
+			// (support+k)*100 >= percent*(participants+k)
+			majority = (short + 100 - percent - 1) / (100 - percent)
+		}
+	}
+
+	if majority > needed {
+		needed = majority
+	}
+	return needed
+}
@@ -0,0 +1,109 @@
+package models
+
+import "testing"
+
+// TestThresholdSupport makes sure each question type counts as support only
+// the voters who back the leading option, not every ballot that mentions it
+func TestThresholdSupport(t *testing.T) {
+	ballot := func(participantID string, optionID string) Ballot {
+		return NewBallot("q1", NewParticipant(participantID, ""), optionID)
+	}
+	// ranked adds a participant's ranking, most preferred first
+	ranked := func(ballots []Ballot, participantID string, optionIDs ...string) []Ballot {
+		for i, optionID := range optionIDs {
+			b := ballot(participantID, optionID)
+			b.Rank = i + 1
+			ballots = append(ballots, b)
+		}
+		return ballots
+	}
+	// repeat adds count participants, named by prefix, casting ballots made by cast
+	repeat := func(ballots []Ballot, prefix string, count int, cast func(ballots []Ballot, participantID string) []Ballot) []Ballot {
+		for i := 0; i < count; i++ {
+			ballots = cast(ballots, prefix+string(rune('0'+i)))
+		}
+		return ballots
+	}
+
+	var schulze []Ballot
+	schulze = repeat(schulze, "abc", 4, func(b []Ballot, p string) []Ballot { return ranked(b, p, "a", "b", "c") })
+	schulze = repeat(schulze, "bca", 3, func(b []Ballot, p string) []Ballot { return ranked(b, p, "b", "c", "a") })
+	schulze = repeat(schulze, "cba", 3, func(b []Ballot, p string) []Ballot { return ranked(b, p, "c", "b", "a") })
+
+	scored := func(b []Ballot, p string, optionID string, score int) []Ballot {
+		s := ballot(p, optionID)
+		s.Score = score
+		return append(b, s)
+	}
+	var scores []Ballot
+	// 5 on a 1 to 5 scale from three voters, the middle 3 from four more
+	scores = repeat(scores, "high", 3, func(b []Ballot, p string) []Ballot { return scored(b, p, "a", 5) })
+	scores = repeat(scores, "mid", 4, func(b []Ballot, p string) []Ballot { return scored(b, p, "a", 3) })
+
+	answered := func(b []Ballot, p string, optionID string, availability Availability) []Ballot {
+		a := ballot(p, optionID)
+		a.Availability = availability
+		return append(b, a)
+	}
+	var availability []Ballot
+	availability = repeat(availability, "yes", 3, func(b []Ballot, p string) []Ballot { return answered(b, p, "a", AvailableYes) })
+	availability = repeat(availability, "maybe", 3, func(b []Ballot, p string) []Ballot { return answered(b, p, "a", AvailableMaybe) })
+	availability = repeat(availability, "no", 2, func(b []Ballot, p string) []Ballot { return answered(b, p, "a", AvailableNo) })
+
+	var approvals []Ballot
+	approvals = repeat(approvals, "ab", 3, func(b []Ballot, p string) []Ballot { return append(b, ballot(p, "a"), ballot(p, "b")) })
+	approvals = repeat(approvals, "b", 2, func(b []Ballot, p string) []Ballot { return append(b, ballot(p, "b")) })
+
+	tests := []struct {
+		name         string
+		questionType QuestionType
+		options      []string
+		ballots      []Ballot
+		majority     Majority
+		percent      int
+		winner       string
+		participants int
+		support      int
+		met          bool
+	}{
+		// b beats a 6 to 4 and c 7 to 3, so 6 of 10 back it
+		{"SchulzeAbsolute", SchulzeQuestion, []string{"a", "b", "c"}, schulze, AbsoluteMajority, 0, "b", 10, 6, true},
+		{"SchulzeSuper", SchulzeQuestion, []string{"a", "b", "c"}, schulze, Supermajority, 67, "", 10, 6, false},
+		{"ScoreAboveMiddle", ScoreQuestion, []string{"a"}, scores, AbsoluteMajority, 0, "", 7, 3, false},
+		{"AvailabilityYes", AvailabilityQuestion, []string{"a"}, availability, AbsoluteMajority, 0, "", 8, 3, false},
+		{"Approval", ApprovalQuestion, []string{"a", "b"}, approvals, AbsoluteMajority, 0, "b", 5, 5, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			question := NewQuestion("q1", "e1", "Which?")
+			question.Type = test.questionType
+			if test.questionType == ScoreQuestion {
+				question.ScaleMin, question.ScaleMax = 1, 5
+			}
+			question.Majority, question.MajorityPercent = test.majority, test.percent
+			for _, id := range test.options {
+				question.Options = append(question.Options, NewOption(id, "q1", id))
+			}
+			question.Ballots = test.ballots
+			CountVotes(question.Options, question.Ballots)
+
+			result := question.Result()
+			threshold := result.Threshold
+			if threshold == nil {
+				t.Fatalf("result has no threshold: %+v", result)
+			}
+			if threshold.Participants != test.participants || threshold.Support != test.support || threshold.Met != test.met {
+				t.Errorf("threshold = %+v, want %d participants, support %d and met %v", *threshold, test.participants, test.support, test.met)
+			}
+
+			var winner string
+			if len(result.Winners) == 1 {
+				winner = result.Winners[0].ID
+			}
+			if winner != test.winner || len(result.Winners) > 1 {
+				t.Errorf("winners = %+v, want %q", result.Winners, test.winner)
+			}
+		})
+	}
+}
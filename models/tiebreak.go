@@ -0,0 +1,151 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/evoteum/planzoco/go/planzoco/tally"
+)
+
+// TieBreak is how a question settles a tie for the win
+type TieBreak string
+
+const (
+	// KeepTie leaves every tied option as a winner
+	KeepTie TieBreak = "keep"
+	// EarliestOption makes the tied option that was added first the winner
+	EarliestOption TieBreak = "earliest"
+	// RandomDraw picks one of the tied options by a draw seeded with the
+	// question's ID, so anyone can repeat it
+	RandomDraw TieBreak = "random"
+	// OrganizerDecides leaves the tie until the organizer picks one of the
+	// tied options once voting has closed
+	OrganizerDecides TieBreak = "organizer"
+)
+
+// TieBreaks lists every tie-break policy, in the order they are offered
+var TieBreaks = []TieBreak{KeepTie, EarliestOption, RandomDraw, OrganizerDecides}
+
+// Valid reports whether t is a known tie-break policy
+func (t TieBreak) Valid() bool {
+	return t == KeepTie || t == EarliestOption || t == RandomDraw || t == OrganizerDecides
+}
+
+// Label returns the name of the policy shown to people
+func (t TieBreak) Label() string {
+	switch t {
+	case EarliestOption:
+		return "Earliest option wins"
+	case RandomDraw:
+		return "Random draw"
+	case OrganizerDecides:
+		return "Organizer decides"
+	default:
+		return "Keep the tie"
+	}
+}
+
+// Tie is how a tie for the win was settled
+type Tie struct {
+	Options []Option `json:"options" dynamodbav:"options"`                     // Every option that tied
+	Policy  TieBreak `json:"policy" dynamodbav:"policy"`                       // The policy that settled it
+	Seed    int64    `json:"seed,omitempty" dynamodbav:"seed,omitempty"`       // Random draws only
+	Settled bool     `json:"settled,omitempty" dynamodbav:"settled,omitempty"` // Whether the organizer has picked the winner
+}
+
+// Description says how the tie was settled, eg "Settled by a random draw
+// with seed 42"
+func (t Tie) Description() string {
+	switch t.Policy {
+	case EarliestOption:
+		return "Settled in favour of the option added first"
+	case RandomDraw:
+		return fmt.Sprintf("Settled by a random draw with seed %d", t.Seed)
+	case OrganizerDecides:
+		if t.Settled {
+			return "Settled by the organizer"
+		}
+		return "Left for the organizer to settle"
+	default:
+		return "Kept as a tie"
+	}
+}
+
+// TieBreakPolicy returns how the question settles a tie for the win.
+// Questions without a policy keep the tie.
+func (q Question) TieBreakPolicy() TieBreak {
+	if q.TieBreak == "" {
+		return KeepTie
+	}
+	return q.TieBreak
+}
+
+// breakTie settles a tie among the result's winners by the question's policy,
+// recording how in the result's Tie
+func (q Question) breakTie(result Result) Result {
+	if len(result.Winners) < 2 {
+		return result
+	}
+
+	tie := &Tie{Options: result.Winners, Policy: q.TieBreakPolicy()}
+	switch tie.Policy {
+	case EarliestOption:
+		// Options come in the order they were added
+		result.Winners = []Option{q.earliestOf(tie.Options)}
+	case RandomDraw:
+		tie.Seed = tally.Seed(q.ID)
+		ids := make([]string, len(tie.Options))
+		for i, option := range tie.Options {
+			ids[i] = option.ID
+		}
+		winner := tally.Draw(ids, tie.Seed)
+		for _, option := range tie.Options {
+			if option.ID == winner {
+				result.Winners = []Option{option}
+			}
+		}
+	case KeepTie, OrganizerDecides:
+		// The tie stands, for good or until the organizer breaks it
+	}
+
+	result.Tie = tie
+	return result
+}
+
+// earliestOf returns whichever of the options was added to the question first
+func (q Question) earliestOf(options []Option) Option {
+	tied := make(map[string]bool, len(options))
+	for _, option := range options {
+		tied[option.ID] = true
+	}
+	for _, option := range q.Options {
+		if tied[option.ID] {
+			return option
+		}
+	}
+	return options[0]
+}
+
+// AwaitsOrganizer reports whether the decision is a tie left for the
+// organizer to break
+func (d Decision) AwaitsOrganizer() bool {
+	return d.Tie != nil && d.Tie.Policy == OrganizerDecides && !d.Tie.Settled
+}
+
+// BreakTie settles a decided tie the organizer was left to break, with
+// optionID as the winner
+func (d Decision) BreakTie(optionID string) (Decision, error) {
+	if !d.AwaitsOrganizer() {
+		return d, errors.New("there is no tie for the organizer to break")
+	}
+
+	for _, option := range d.Winners {
+		if option.ID == optionID {
+			tie := *d.Tie
+			tie.Settled = true
+			d.Winners, d.Tie = []Option{option}, &tie
+			return d, nil
+		}
+	}
+	return d, fmt.Errorf("option %s is not one of the tied options", optionID)
+}
@@ -0,0 +1,150 @@
+package models
+
+import "fmt"
+
+// Veto records that a participant can't go along with an option, such as a
+// date they can't make. A participant's vetoes on a question are always
+// replaced together, like their ballots.
+type Veto struct {
+	DynamoItem
+	QuestionID      string     `json:"question_id" dynamodbav:"question_id"`
+	OptionID        string     `json:"option_id" dynamodbav:"option_id"`
+	ParticipantID   string     `json:"-" dynamodbav:"participant_id"`
+	ParticipantName string     `json:"participant_name,omitempty" dynamodbav:"participant_name"`
+	EntityType      EntityType `json:"-" dynamodbav:"entity_type"`
+}
+
+// NewVeto creates a new Veto with the proper PK/SK pattern. All vetoes of one
+// participant on one question share a partition key.
+func NewVeto(questionID string, participant Participant, optionID string) Veto {
+	return Veto{
+		DynamoItem: DynamoItem{
+			PK: VetoPartition(questionID, participant.ID),
+			SK: string(OptionEntity) + "#" + optionID,
+		},
+		QuestionID:      questionID,
+		OptionID:        optionID,
+		ParticipantID:   participant.ID,
+		ParticipantName: participant.Name,
+		EntityType:      VetoEntity,
+	}
+}
+
+// VetoPartition returns the partition key shared by a participant's vetoes on a question
+func VetoPartition(questionID string, participantID string) string {
+	return string(VetoEntity) + "#" + questionID + "#" + participantID
+}
+
+// CountVetoes sets the Vetoes of every option to the number of vetoes against it
+func CountVetoes(options []Option, vetoes []Veto) {
+	counts := make(map[string]int, len(options))
+	for _, veto := range vetoes {
+		counts[veto.OptionID]++
+	}
+	for i := range options {
+		options[i].Vetoes = counts[options[i].ID]
+	}
+}
+
+// AllowsVetoes reports whether participants can veto the question's options
+func (q Question) AllowsVetoes() bool {
+	return q.VetoLimit > 0
+}
+
+// Vetoed reports whether the option has as many vetoes as the question's
+// limit, which rules it out of winning
+func (q Question) Vetoed(option Option) bool {
+	return q.AllowsVetoes() && option.Vetoes >= q.VetoLimit
+}
+
+// VetoesFor returns the vetoes cast on the question by one participant
+func (q Question) VetoesFor(participantID string) []Veto {
+	var vetoes []Veto
+	for _, veto := range q.Vetoes {
+		if veto.ParticipantID == participantID {
+			vetoes = append(vetoes, veto)
+		}
+	}
+	return vetoes
+}
+
+// VetoedBy reports whether the participant has vetoed the option
+func (q Question) VetoedBy(participantID string, optionID string) bool {
+	for _, veto := range q.VetoesFor(participantID) {
+		if veto.OptionID == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+// Vetoers returns the display names of everyone who vetoed an option
+func (q Question) Vetoers(optionID string) []string {
+	var names []string
+	for _, veto := range q.Vetoes {
+		if veto.OptionID == optionID {
+			names = append(names, NewParticipant(veto.ParticipantID, veto.ParticipantName).DisplayName())
+		}
+	}
+	return names
+}
+
+// ValidateVetoes checks that a participant's vetoes for the question are
+// allowed: the question takes vetoes, every option belongs to the question,
+// and no option is vetoed twice.
+func (q Question) ValidateVetoes(vetoes []Veto) error {
+	if !q.AllowsVetoes() && len(vetoes) > 0 {
+		return fmt.Errorf("question %s takes no vetoes", q.ID)
+	}
+
+	options := make(map[string]bool, len(q.Options))
+	for _, opt := range q.Options {
+		options[opt.ID] = !opt.Pending
+	}
+
+	seen := make(map[string]bool, len(vetoes))
+	for _, veto := range vetoes {
+		approved, ok := options[veto.OptionID]
+		if veto.QuestionID != q.ID || !ok {
+			return fmt.Errorf("option %s does not belong to question %s", veto.OptionID, q.ID)
+		}
+		if !approved {
+			return fmt.Errorf("option %s is awaiting the organizer's approval", veto.OptionID)
+		}
+		if seen[veto.OptionID] {
+			return fmt.Errorf("option %s vetoed more than once", veto.OptionID)
+		}
+		seen[veto.OptionID] = true
+	}
+	return nil
+}
+
+// withoutVetoed returns the question without the options vetoed out of
+// winning and the ballots cast for them, to be counted among the rest
+func (q Question) withoutVetoed() Question {
+	if !q.AllowsVetoes() {
+		return q
+	}
+
+	vetoed := make(map[string]bool)
+	var options []Option
+	for _, option := range q.Options {
+		if q.Vetoed(option) {
+			vetoed[option.ID] = true
+			continue
+		}
+		options = append(options, option)
+	}
+	if len(vetoed) == 0 {
+		return q
+	}
+
+	var ballots []Ballot
+	for _, ballot := range q.Ballots {
+		if !vetoed[ballot.OptionID] {
+			ballots = append(ballots, ballot)
+		}
+	}
+	q.Options, q.Ballots = options, ballots
+	return q
+}
@@ -0,0 +1,80 @@
+package pubsub
+
+import "sync"
+
+// subscriberBuffer is how many messages a slow subscriber may fall behind
+// before further messages to it are dropped
+const subscriberBuffer = 16
+
+// Message is one update published on a topic
+type Message struct {
+	Type string // eg "option.created", sent as the SSE event name
+	Data any    // Encoded as JSON
+}
+
+// Broker fans messages published on a topic out to everyone subscribed to it.
+// Hub keeps subscribers in process memory; running several replicas needs a
+// Broker backed by something they share, such as Redis or NATS.
+type Broker interface {
+	// Publish sends a message to the current subscribers of a topic without
+	// waiting for them to receive it
+	Publish(topic string, message Message)
+	// Subscribe returns a channel receiving the topic's messages, and a
+	// function that ends the subscription and closes the channel
+	Subscribe(topic string) (<-chan Message, func())
+}
+
+// Hub is an in-process Broker
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Message]struct{}
+}
+
+// NewHub creates a Hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan Message]struct{})}
+}
+
+// Publish delivers a message to every subscriber of the topic. Subscribers
+// that are too far behind miss it rather than holding up the publisher.
+func (h *Hub) Publish(topic string, message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscriber := range h.subscribers[topic] {
+		select {
+		case subscriber <- message:
+		default:
+		}
+	}
+}
+
+// Subscribe starts receiving the messages published on a topic
+func (h *Hub) Subscribe(topic string) (<-chan Message, func()) {
+	subscriber := make(chan Message, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[chan Message]struct{})
+	}
+	h.subscribers[topic][subscriber] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.subscribers[topic], subscriber)
+			if len(h.subscribers[topic]) == 0 {
+				delete(h.subscribers, topic)
+			}
+			close(subscriber)
+		})
+	}
+
+	return subscriber, unsubscribe
+}
+
+var _ Broker = (*Hub)(nil)
@@ -1,43 +1,108 @@
 package routes
 
 import (
+	"github.com/evoteum/planzoco/go/planzoco/databases"
 	"github.com/evoteum/planzoco/go/planzoco/handlers"
+	"github.com/evoteum/planzoco/go/planzoco/pubsub"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes() *gin.Engine {
+func SetupRoutes(store databases.Store, broker pubsub.Broker) *gin.Engine {
 	r := gin.Default()
 	r.LoadHTMLGlob("templates/*")
 
 	// Serve static files from the static directory
 	r.Static("/static", "./static")
 
+	h := handlers.New(store, broker)
+
 	// Event routes
-	r.GET("/", handlers.ListEvents)
-	r.GET("/events/new", handlers.NewEventForm)
-	r.POST("/events", handlers.CreateEvent)
-	r.GET("/events/:id", handlers.GetEvent)
-	r.GET("/events/:id/edit", handlers.UpdateEventForm)
-	r.POST("/events/:id", handlers.UpdateEvent)
-	r.POST("/events/:id/delete", handlers.DeleteEvent)
+	r.GET("/", h.ListEvents)
+	r.GET("/events/new", h.NewEventForm)
+	r.POST("/events", h.CreateEvent)
+	r.GET("/events/:id", h.GetEvent)
+	r.GET("/events/:id/admin/:token", h.ClaimOrganizer)
+	r.GET("/events/:id/updates", h.StreamEvent)
+	r.GET("/events/:id/edit", h.RequireEventOrganizer, h.UpdateEventForm)
+	r.POST("/events/:id", h.RequireEventOrganizer, h.UpdateEvent)
+	r.POST("/events/:id/delete", h.RequireEventOrganizer, h.DeleteEvent)
 
 	// Question routes
-	r.POST("/events/:id/questions", handlers.CreateQuestion)
-	r.GET("/questions/:id", handlers.GetQuestion)
-	r.GET("/questions/:id/edit", handlers.UpdateQuestionForm)
-	r.POST("/questions/:id", handlers.UpdateQuestion)
-	r.POST("/questions/:id/delete", handlers.DeleteQuestion)
+	r.POST("/events/:id/questions", h.CreateQuestion)
+	r.GET("/questions/:id", h.GetQuestion)
+	r.GET("/questions/:id/updates", h.StreamQuestion)
+	r.GET("/questions/:id/edit", h.RequireQuestionOrganizer, h.UpdateQuestionForm)
+	r.POST("/questions/:id", h.RequireQuestionOrganizer, h.UpdateQuestion)
+	r.POST("/questions/:id/delete", h.RequireQuestionOrganizer, h.DeleteQuestion)
+	r.POST("/questions/:id/tie", h.RequireQuestionOrganizer, h.BreakTie)
+	r.POST("/questions/:id/reveal", h.RequireQuestionOrganizer, h.RevealResults)
 
 	// Option routes
-	r.POST("/questions/:id/options", handlers.CreateOption)
-	r.GET("/options/:id/edit", handlers.UpdateOptionForm)
-	r.POST("/options/:id", handlers.UpdateOption)
-	r.POST("/options/:id/delete", handlers.DeleteOption)
-	r.POST("/options/:id/vote", handlers.VoteOption)
+	r.POST("/questions/:id/options", h.CreateOption)
+	r.GET("/options/:id/edit", h.RequireOptionOrganizer, h.UpdateOptionForm)
+	r.POST("/options/:id", h.RequireOptionOrganizer, h.UpdateOption)
+	r.POST("/options/:id/delete", h.RequireOptionOrganizer, h.DeleteOption)
+	r.POST("/options/:id/approve", h.RequireOptionOrganizer, h.ApproveOption)
+	r.POST("/options/:id/reject", h.RequireOptionOrganizer, h.RejectOption)
+	r.POST("/options/:id/merge", h.RequireOptionOrganizer, h.MergeOption)
+	r.POST("/options/:id/vote", h.VoteOption)
+	r.POST("/options/:id/vote/delete", h.WithdrawVote)
+	r.POST("/options/:id/veto", h.VetoOption)
+	r.POST("/options/:id/veto/delete", h.WithdrawVeto)
+	r.POST("/questions/:id/ranking", h.RankOptions)
+	r.POST("/questions/:id/approvals", h.ApproveOptions)
+	r.POST("/questions/:id/availability", h.MarkAvailability)
+	r.POST("/questions/:id/scores", h.ScoreOptions)
 
-	r.GET("/health", handlers.HealthCheck)
+	// Participant routes
+	r.POST("/participant", h.UpdateParticipant)
+
+	// JSON API
+	api := r.Group(handlers.APIPrefix)
+
+	api.GET("/events", h.APIListEvents)
+	api.POST("/events", h.APICreateEvent)
+	api.GET("/events/:id", h.APIGetEvent)
+	api.PUT("/events/:id", h.RequireEventOrganizer, h.APIUpdateEvent)
+	api.DELETE("/events/:id", h.RequireEventOrganizer, h.APIDeleteEvent)
+
+	api.GET("/events/:id/questions", h.APIListQuestions)
+	api.POST("/events/:id/questions", h.APICreateQuestion)
+	api.GET("/questions/:id", h.APIGetQuestion)
+	api.PUT("/questions/:id", h.RequireQuestionOrganizer, h.APIUpdateQuestion)
+	api.DELETE("/questions/:id", h.RequireQuestionOrganizer, h.APIDeleteQuestion)
+	api.GET("/questions/:id/result", h.APIGetResult)
+	api.POST("/questions/:id/tie", h.RequireQuestionOrganizer, h.APIBreakTie)
+	api.POST("/questions/:id/reveal", h.RequireQuestionOrganizer, h.APIRevealResults)
+
+	api.GET("/questions/:id/options", h.APIListOptions)
+	api.POST("/questions/:id/options", h.APICreateOption)
+	api.GET("/options/:id", h.APIGetOption)
+	api.PUT("/options/:id", h.RequireOptionOrganizer, h.APIUpdateOption)
+	api.DELETE("/options/:id", h.RequireOptionOrganizer, h.APIDeleteOption)
 
+	api.GET("/questions/:id/suggestions", h.RequireQuestionOrganizer, h.APIListSuggestions)
+	api.POST("/options/:id/approve", h.RequireOptionOrganizer, h.APIApproveOption)
+	api.POST("/options/:id/reject", h.RequireOptionOrganizer, h.APIRejectOption)
+	api.POST("/options/:id/merge", h.RequireOptionOrganizer, h.APIMergeOption)
+
+	api.GET("/questions/:id/vote", h.APIGetVote)
+	api.PUT("/questions/:id/vote", h.APIPutVote)
+	api.DELETE("/questions/:id/vote", h.APIDeleteVote)
+
+	api.GET("/questions/:id/vetoes", h.APIGetVetoes)
+	api.PUT("/questions/:id/vetoes", h.APIPutVetoes)
+	api.DELETE("/questions/:id/vetoes", h.APIDeleteVetoes)
+
+	api.GET("/participant", h.APIGetParticipant)
+	api.PUT("/participant", h.APIUpdateParticipant)
+
+	// API documentation
+	r.GET(handlers.OpenAPIPath, handlers.OpenAPISpec)
+	r.GET(handlers.APIDocsPath, handlers.APIDocs)
+
+	r.GET("/health", handlers.HealthCheck)
 
 	return r
 }
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"os"
+	"testing"
+
+	"github.com/evoteum/planzoco/go/planzoco/databases"
+	"github.com/evoteum/planzoco/go/planzoco/handlers"
+	"github.com/evoteum/planzoco/go/planzoco/pubsub"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesDocumented makes sure every API route is described in the
+// OpenAPI document
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The templates are loaded relative to the repository root
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	r := SetupRoutes(databases.NewMemoryStore(), pubsub.NewHub())
+
+	for _, route := range handlers.UndocumentedRoutes(r.Routes()) {
+		t.Errorf("API route missing from the OpenAPI document: %s", route)
+	}
+}
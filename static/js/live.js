@@ -0,0 +1,83 @@
+// Keeps a page up to date without reloading it. The body names the stream to
+// follow in data-updates; whenever it reports a change, the page is fetched
+// again and every element marked data-live is swapped for its fresh copy, so
+// what people are typing elsewhere on the page is left alone.
+(function () {
+    const body = document.body;
+    if (!body.dataset.updates || !window.EventSource) {
+        return;
+    }
+
+    const refreshOn = [
+        "event.updated",
+        "question.created",
+        "question.updated",
+        "question.deleted",
+        "option.created",
+        "option.updated",
+        "option.deleted",
+        "option.suggested",
+        "votes.changed"
+    ];
+
+    let loading = false;
+    let stale = false;
+
+    function refresh() {
+        if (loading) {
+            stale = true;
+            return;
+        }
+        loading = true;
+        stale = false;
+
+        fetch(window.location.href, { credentials: "same-origin" })
+            .then(function (response) {
+                if (!response.ok) {
+                    throw new Error(response.statusText);
+                }
+                return response.text();
+            })
+            .then(function (html) {
+                const fresh = new DOMParser().parseFromString(html, "text/html");
+                document.querySelectorAll("[data-live]").forEach(function (element) {
+                    if (element.dataset.dirty) {
+                        return;
+                    }
+                    const replacement = fresh.getElementById(element.id);
+                    if (replacement) {
+                        element.replaceWith(document.importNode(replacement, true));
+                    }
+                });
+            })
+            .catch(function () {})
+            .finally(function () {
+                loading = false;
+                if (stale) {
+                    refresh();
+                }
+            });
+    }
+
+    // Leave regions alone once someone starts changing inputs in them, like
+    // the rank selectors, so a refresh doesn't throw their choices away
+    document.addEventListener("change", function (event) {
+        const region = event.target.closest("[data-live]");
+        if (region) {
+            region.dataset.dirty = "true";
+        }
+    });
+
+    const source = new EventSource(body.dataset.updates);
+    refreshOn.forEach(function (type) {
+        if (type !== body.dataset.goneOn) {
+            source.addEventListener(type, refresh);
+        }
+    });
+    if (body.dataset.goneOn) {
+        source.addEventListener(body.dataset.goneOn, function () {
+            source.close();
+            window.location.href = body.dataset.goneTo;
+        });
+    }
+})();
@@ -0,0 +1,28 @@
+package tally
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+)
+
+// Seed derives the seed of a draw from a name, such as the ID of what the
+// draw decides, so anyone who knows the name can repeat the draw
+func Seed(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
+
+// Draw picks one of the candidates at random, using the seed. The candidates
+// are sorted first, so the same seed and candidates always give the same pick,
+// whatever order they come in. There is no pick without candidates.
+func Draw(candidates []string, seed int64) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	return sorted[rand.New(rand.NewSource(seed)).Intn(len(sorted))]
+}
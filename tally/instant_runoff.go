@@ -0,0 +1,159 @@
+// Package tally implements the counting methods behind planzoco's question
+// types. It only deals in candidate IDs, rankings and scores, so it has no
+// knowledge of how questions, options and ballots are stored.
+package tally
+
+import "sort"
+
+// Round is one counting round of an instant-runoff tally
+type Round struct {
+	// Votes maps every candidate still in the count to the ballots ranking
+	// it highest among those still in the count
+	Votes map[string]int
+	// Exhausted is the number of ballots that rank none of the candidates
+	// still in the count
+	Exhausted int
+	// Eliminated is the candidate dropped after this round, empty in the
+	// final round
+	Eliminated string
+}
+
+// InstantRunoffResult is the outcome of an instant-runoff tally
+type InstantRunoffResult struct {
+	Winners []string // In candidate order; more than one only for a tie
+	Rounds  []Round
+}
+
+// InstantRunoff tallies ranked ballots by instant-runoff voting. candidates
+// lists every candidate, and each ballot lists candidate IDs from most to
+// least preferred; unranked candidates are simply left out, and unknown or
+// repeated IDs are ignored.
+//
+// Each round counts every ballot for its highest-ranked candidate still in the
+// count. The count stops when:
+//
+//   - a candidate holds more than half of the ballots that are not exhausted,
+//     and wins;
+//   - every remaining candidate holds the same number of votes, and they tie;
+//   - no ballot counts for anyone, and there is no winner.
+//
+// Otherwise the candidate with the fewest votes is eliminated and the next
+// round starts. A tie for the fewest votes is broken deterministically:
+//
+//  1. among the tied candidates, eliminate the one with the fewest votes in
+//     the most recent earlier round in which their votes differed;
+//  2. if they held the same votes in every round, eliminate the one listed
+//     latest in candidates.
+func InstantRunoff(candidates []string, ballots [][]string) InstantRunoffResult {
+	var result InstantRunoffResult
+
+	continuing := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		continuing[candidate] = true
+	}
+	order := make(map[string]int, len(candidates))
+	for i, candidate := range candidates {
+		if _, ok := order[candidate]; !ok {
+			order[candidate] = i
+		}
+	}
+
+	for len(continuing) > 0 {
+		round := Round{Votes: make(map[string]int, len(continuing))}
+		for candidate := range continuing {
+			round.Votes[candidate] = 0
+		}
+		active := 0
+		for _, ballot := range ballots {
+			if choice, ok := topChoice(ballot, continuing); ok {
+				round.Votes[choice]++
+				active++
+			} else {
+				round.Exhausted++
+			}
+		}
+		result.Rounds = append(result.Rounds, round)
+
+		if active == 0 {
+			return result
+		}
+
+		remaining := sortedCandidates(continuing, order)
+		fewest, most := round.Votes[remaining[0]], round.Votes[remaining[0]]
+		for _, candidate := range remaining {
+			fewest = min(fewest, round.Votes[candidate])
+			most = max(most, round.Votes[candidate])
+		}
+
+		if most*2 > active {
+			for _, candidate := range remaining {
+				if round.Votes[candidate] == most {
+					result.Winners = []string{candidate}
+				}
+			}
+			return result
+		}
+
+		if fewest == most {
+			result.Winners = remaining
+			return result
+		}
+
+		var tied []string
+		for _, candidate := range remaining {
+			if round.Votes[candidate] == fewest {
+				tied = append(tied, candidate)
+			}
+		}
+		eliminated := breakEliminationTie(tied, result.Rounds)
+
+		result.Rounds[len(result.Rounds)-1].Eliminated = eliminated
+		delete(continuing, eliminated)
+	}
+
+	return result
+}
+
+// topChoice returns the highest-ranked candidate of the ballot still in the count
+func topChoice(ballot []string, continuing map[string]bool) (string, bool) {
+	for _, candidate := range ballot {
+		if continuing[candidate] {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// breakEliminationTie picks which of the candidates tied for the fewest votes
+// is eliminated, following the rules documented on InstantRunoff. tied must
+// be in candidate order.
+func breakEliminationTie(tied []string, rounds []Round) string {
+	for r := len(rounds) - 2; r >= 0 && len(tied) > 1; r-- {
+		fewest := rounds[r].Votes[tied[0]]
+		for _, candidate := range tied {
+			fewest = min(fewest, rounds[r].Votes[candidate])
+		}
+
+		var stillTied []string
+		for _, candidate := range tied {
+			if rounds[r].Votes[candidate] == fewest {
+				stillTied = append(stillTied, candidate)
+			}
+		}
+		tied = stillTied
+	}
+
+	return tied[len(tied)-1]
+}
+
+// sortedCandidates returns the candidates in the set in candidate order
+func sortedCandidates(set map[string]bool, order map[string]int) []string {
+	candidates := make([]string, 0, len(set))
+	for candidate := range set {
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return order[candidates[i]] < order[candidates[j]]
+	})
+	return candidates
+}
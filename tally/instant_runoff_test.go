@@ -0,0 +1,107 @@
+package tally
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstantRunoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		ballots    [][]string
+		winners    []string
+		rounds     []Round
+	}{
+		{
+			name:       "majority in the first round",
+			candidates: []string{"A", "B", "C"},
+			ballots:    election(voters(3, "A"), voters(1, "B"), voters(1, "C")),
+			winners:    []string{"A"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 3, "B": 1, "C": 1}},
+			},
+		},
+		{
+			name:       "votes move on from the eliminated candidate",
+			candidates: []string{"A", "B", "C"},
+			ballots:    election(voters(2, "A"), voters(2, "B"), voters(1, "CA")),
+			winners:    []string{"A"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 2, "B": 2, "C": 1}, Eliminated: "C"},
+				{Votes: map[string]int{"A": 3, "B": 2}},
+			},
+		},
+		{
+			name:       "tie for the fewest votes broken by the latest round they differed",
+			candidates: []string{"A", "C", "B", "D"},
+			ballots:    election(voters(4, "A"), voters(3, "B"), voters(2, "CB"), voters(1, "DC")),
+			winners:    []string{"B"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 4, "B": 3, "C": 2, "D": 1}, Eliminated: "D"},
+				// B and C are level, but C had fewer votes in the first round
+				{Votes: map[string]int{"A": 4, "B": 3, "C": 3}, Eliminated: "C"},
+				{Votes: map[string]int{"A": 4, "B": 5}, Exhausted: 1},
+			},
+		},
+		{
+			name:       "tie for the fewest votes in every round eliminates the candidate listed latest",
+			candidates: []string{"A", "C", "B"},
+			ballots:    election(voters(2, "A"), voters(1, "BA"), voters(1, "CB")),
+			winners:    []string{"A"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 2, "B": 1, "C": 1}, Eliminated: "B"},
+				{Votes: map[string]int{"A": 3, "C": 1}},
+			},
+		},
+		{
+			name:       "tie in the final round",
+			candidates: []string{"A", "B", "C"},
+			ballots:    election(voters(2, "A"), voters(2, "B"), voters(1, "C")),
+			winners:    []string{"A", "B"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 2, "B": 2, "C": 1}, Eliminated: "C"},
+				{Votes: map[string]int{"A": 2, "B": 2}, Exhausted: 1},
+			},
+		},
+		{
+			name:       "tie between everyone in the first round",
+			candidates: []string{"A", "B"},
+			ballots:    election(voters(1, "AB"), voters(1, "BA")),
+			winners:    []string{"A", "B"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 1, "B": 1}},
+			},
+		},
+		{
+			name:       "unknown and repeated candidates are ignored",
+			candidates: []string{"A", "B"},
+			ballots:    [][]string{{"X", "A", "A"}, {"B"}, {"A", "B"}},
+			winners:    []string{"A"},
+			rounds: []Round{
+				{Votes: map[string]int{"A": 2, "B": 1}},
+			},
+		},
+		{
+			name:       "no ballots",
+			candidates: []string{"A", "B"},
+			winners:    nil,
+			rounds: []Round{
+				{Votes: map[string]int{"A": 0, "B": 0}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := InstantRunoff(test.candidates, test.ballots)
+
+			if !reflect.DeepEqual(result.Winners, test.winners) {
+				t.Errorf("winners = %v, want %v", result.Winners, test.winners)
+			}
+			if !reflect.DeepEqual(result.Rounds, test.rounds) {
+				t.Errorf("rounds = %+v, want %+v", result.Rounds, test.rounds)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package tally
+
+// SchulzeResult is the outcome of a Schulze tally. The tables are indexed in
+// candidate order, with repeated candidates left out.
+type SchulzeResult struct {
+	Winners []string // In candidate order; more than one only for a tie
+	// Preferences[i][j] is the number of ballots ranking candidate i above
+	// candidate j
+	Preferences [][]int
+	// StrongestPaths[i][j] is the strength of the strongest path from
+	// candidate i to candidate j, 0 if there is none
+	StrongestPaths [][]int
+}
+
+// Schulze tallies ranked ballots by the Schulze (beatpath) method, which
+// always elects the Condorcet winner when there is one. candidates lists every
+// candidate, and each ballot lists candidate IDs from most to least preferred.
+// A ballot ranks every candidate it lists above every candidate it leaves out,
+// and expresses no preference between those it leaves out; unknown or repeated
+// IDs are ignored.
+//
+// The count works in three steps:
+//
+//  1. for every pair of candidates, count the ballots preferring one to the
+//     other;
+//  2. a candidate beats another directly when more ballots prefer it than the
+//     other way round, with the strength of that link being the number of
+//     ballots preferring it (winning votes). A path's strength is that of its
+//     weakest link, and the strongest path between every pair is found with
+//     the Floyd–Warshall algorithm;
+//  3. the winners are the candidates whose strongest path to every other
+//     candidate is at least as strong as the strongest path back.
+//
+// There is no winner without any ballot expressing a preference.
+func Schulze(candidates []string, ballots [][]string) SchulzeResult {
+	index := make(map[string]int, len(candidates))
+	var unique []string
+	for _, candidate := range candidates {
+		if _, ok := index[candidate]; !ok {
+			index[candidate] = len(unique)
+			unique = append(unique, candidate)
+		}
+	}
+	candidates = unique
+
+	n := len(candidates)
+	result := SchulzeResult{
+		Preferences:    squareTable(n),
+		StrongestPaths: squareTable(n),
+	}
+
+	preferred := false
+	for _, ballot := range ballots {
+		ranked := make([]bool, n)
+		var order []int
+		for _, candidate := range ballot {
+			i, ok := index[candidate]
+			if !ok || ranked[i] {
+				continue
+			}
+			ranked[i] = true
+			order = append(order, i)
+		}
+
+		for position, i := range order {
+			// Above everyone listed later, and everyone left out
+			for _, j := range order[position+1:] {
+				result.Preferences[i][j]++
+				preferred = true
+			}
+			for j := range candidates {
+				if !ranked[j] {
+					result.Preferences[i][j]++
+					preferred = true
+				}
+			}
+		}
+	}
+
+	if !preferred {
+		return result
+	}
+
+	paths := result.StrongestPaths
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && result.Preferences[i][j] > result.Preferences[j][i] {
+				paths[i][j] = result.Preferences[i][j]
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j == i || j == k {
+					continue
+				}
+				paths[i][j] = max(paths[i][j], min(paths[i][k], paths[k][j]))
+			}
+		}
+	}
+
+	for i, candidate := range candidates {
+		wins := true
+		for j := 0; j < n && wins; j++ {
+			wins = j == i || paths[i][j] >= paths[j][i]
+		}
+		if wins {
+			result.Winners = append(result.Winners, candidate)
+		}
+	}
+
+	return result
+}
+
+// squareTable returns an n by n table of zeroes
+func squareTable(n int) [][]int {
+	table := make([][]int, n)
+	for i := range table {
+		table[i] = make([]int, n)
+	}
+	return table
+}
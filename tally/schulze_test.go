@@ -0,0 +1,190 @@
+package tally
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// voters returns count ballots ranking the single-letter candidates in
+// ranking from most to least preferred
+func voters(count int, ranking string) [][]string {
+	ballots := make([][]string, count)
+	for i := range ballots {
+		ballots[i] = strings.Split(ranking, "")
+	}
+	return ballots
+}
+
+// election joins groups of ballots into one election
+func election(groups ...[][]string) [][]string {
+	var ballots [][]string
+	for _, group := range groups {
+		ballots = append(ballots, group...)
+	}
+	return ballots
+}
+
+func TestSchulze(t *testing.T) {
+	tests := []struct {
+		name           string
+		candidates     []string
+		ballots        [][]string
+		winners        []string
+		preferences    [][]int
+		strongestPaths [][]int
+	}{
+		{
+			// https://en.wikipedia.org/wiki/Schulze_method#Example, which has
+			// no Condorcet winner
+			name:       "Wikipedia example",
+			candidates: []string{"A", "B", "C", "D", "E"},
+			ballots: election(
+				voters(5, "ACBED"),
+				voters(5, "ADECB"),
+				voters(8, "BEDAC"),
+				voters(3, "CABED"),
+				voters(7, "CAEBD"),
+				voters(2, "CBADE"),
+				voters(7, "DCEBA"),
+				voters(8, "EBADC"),
+			),
+			winners: []string{"E"},
+			preferences: [][]int{
+				{0, 20, 26, 30, 22},
+				{25, 0, 16, 33, 18},
+				{19, 29, 0, 17, 24},
+				{15, 12, 28, 0, 14},
+				{23, 27, 21, 31, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 28, 28, 30, 24},
+				{25, 0, 28, 33, 24},
+				{25, 29, 0, 29, 24},
+				{25, 28, 28, 0, 24},
+				{25, 28, 28, 31, 0},
+			},
+		},
+		{
+			// Example 1 of Schulze's paper "A new monotonic, clone-independent,
+			// reversal symmetric, and Condorcet-consistent single-winner
+			// election method", which has no Condorcet winner either
+			name:       "Schulze paper example",
+			candidates: []string{"A", "B", "C", "D"},
+			ballots: election(
+				voters(8, "ACDB"),
+				voters(2, "BADC"),
+				voters(4, "CDBA"),
+				voters(4, "DBAC"),
+				voters(3, "DCBA"),
+			),
+			winners: []string{"D"},
+			preferences: [][]int{
+				{0, 8, 14, 10},
+				{13, 0, 6, 2},
+				{7, 15, 0, 12},
+				{11, 19, 9, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 14, 14, 12},
+				{13, 0, 13, 12},
+				{13, 15, 0, 12},
+				{13, 19, 13, 0},
+			},
+		},
+		{
+			name:       "Condorcet winner",
+			candidates: []string{"A", "B", "C"},
+			ballots: election(
+				voters(2, "BAC"),
+				voters(2, "CAB"),
+				voters(1, "ABC"),
+			),
+			winners: []string{"A"},
+			preferences: [][]int{
+				{0, 3, 3},
+				{2, 0, 3},
+				{2, 2, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 3, 3},
+				{0, 0, 3},
+				{0, 0, 0},
+			},
+		},
+		{
+			name:       "cycle of equal strength ties everyone",
+			candidates: []string{"A", "B", "C"},
+			ballots: election(
+				voters(1, "ABC"),
+				voters(1, "BCA"),
+				voters(1, "CAB"),
+			),
+			winners: []string{"A", "B", "C"},
+			preferences: [][]int{
+				{0, 2, 1},
+				{1, 0, 2},
+				{2, 1, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 2, 2},
+				{2, 0, 2},
+				{2, 2, 0},
+			},
+		},
+		{
+			name:       "two-way tie",
+			candidates: []string{"A", "B"},
+			ballots:    election(voters(3, "AB"), voters(3, "BA")),
+			winners:    []string{"A", "B"},
+			preferences: [][]int{
+				{0, 3},
+				{3, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 0},
+				{0, 0},
+			},
+		},
+		{
+			name:       "unlisted candidates rank last",
+			candidates: []string{"A", "B", "C"},
+			ballots:    election(voters(2, "B"), voters(1, "CA")),
+			winners:    []string{"B"},
+			preferences: [][]int{
+				{0, 1, 0},
+				{2, 0, 2},
+				{1, 1, 0},
+			},
+			strongestPaths: [][]int{
+				{0, 0, 0},
+				{2, 0, 2},
+				{1, 0, 0},
+			},
+		},
+		{
+			name:           "no preferences",
+			candidates:     []string{"A", "B"},
+			ballots:        [][]string{{"X"}, {}},
+			winners:        nil,
+			preferences:    [][]int{{0, 0}, {0, 0}},
+			strongestPaths: [][]int{{0, 0}, {0, 0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Schulze(test.candidates, test.ballots)
+
+			if !reflect.DeepEqual(result.Winners, test.winners) {
+				t.Errorf("winners = %v, want %v", result.Winners, test.winners)
+			}
+			if !reflect.DeepEqual(result.Preferences, test.preferences) {
+				t.Errorf("preferences = %v, want %v", result.Preferences, test.preferences)
+			}
+			if !reflect.DeepEqual(result.StrongestPaths, test.strongestPaths) {
+				t.Errorf("strongest paths = %v, want %v", result.StrongestPaths, test.strongestPaths)
+			}
+		})
+	}
+}
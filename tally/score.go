@@ -0,0 +1,34 @@
+package tally
+
+import "sort"
+
+// Mean returns the average of the scores, 0 when there are none
+func Mean(scores []int) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+
+	total := 0
+	for _, score := range scores {
+		total += score
+	}
+	return float64(total) / float64(len(scores))
+}
+
+// Median returns the middle score, or the average of the two middle scores
+// when there is an even number of them, 0 when there are none. scores is left
+// in its original order.
+func Median(scores []int) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[middle])
+	}
+	return float64(sorted[middle-1]+sorted[middle]) / 2
+}
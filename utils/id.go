@@ -5,8 +5,14 @@ import gonanoid "github.com/matoous/go-nanoid/v2"
 const (
 	idLength = 6  // 62^6 possible IDs should be enough for up to 36m customers.
 	alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	tokenLength = 21 // Tokens are secrets, so they need to be unguessable rather than short.
 )
 
 func GenerateID() (string, error) {
 	return gonanoid.Generate(alphabet, idLength)
 }
+
+func GenerateToken() (string, error) {
+	return gonanoid.Generate(alphabet, tokenLength)
+}